@@ -4,29 +4,64 @@ import (
 	"context"
 	"dagger/docker-compose/internal/dagger"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
 // Deploy deploys the Docker Compose stack
 //
 // This function performs:
-// 1. Pull and start containers with --pull always --force-recreate
-// 2. Display container status
+// 1. Pull and start containers with --pull always --force-recreate (or rolling updates)
+// 2. Wait until services with a healthcheck are healthy and the others are running
+// 3. Roll back to the previous images if they do not become ready in time
+// 4. Display container status
 //
 // The --pull always flag ensures images are always re-downloaded from registry,
 // bypassing local cache. This guarantees "latest" tags get the actual latest version.
 //
+// Before deploying, the image ID and configuration hash of every running service
+// of the project are captured. Rollback only restores images: it recreates those
+// services from the compose files of the failed deploy, pinned to their previous
+// images, and removes the services the failed deploy added. The rest of the new
+// configuration (environment, ports, volumes, commands, healthchecks) is kept,
+// so a regression caused by a configuration change is not rolled back; the
+// failure report lists the services whose configuration changed. Rollback
+// requires the previous images to still be present on the host. Nothing is
+// persisted on the host, so secrets injected with WithSecret are never written
+// to disk.
+//
+// With strategy "rolling", services labeled dagger.deploy.strategy=rolling are
+// updated without downtime: a new replica is started next to each running one,
+// and the old replicas are removed once the new ones are healthy. Other services
+// are recreated. Rolling services cannot set container_name or publish fixed
+// host ports, since old and new replicas run side by side.
+//
+//	services:
+//	  api:
+//	    image: registry.example.com/api:latest
+//	    labels:
+//	      dagger.deploy.strategy: rolling
+//
+// With several hosts (see WithContext), the stack is deployed to each of them
+// and a per-host result table is returned.
+//
 // Parameters:
 //   - source: Directory containing the docker-compose.yml file
 //   - composePath: Path to docker-compose.yml relative to source (default: "docker-compose.yml")
 //   - projectName: Docker Compose project name (optional, uses directory name if not set)
+//   - healthTimeout: Seconds to wait for services to become ready (default: 120, 0 disables waiting)
+//   - rollback: Restore the previous images on failure (default: true)
+//   - strategy: "recreate" (default) or "rolling"
 //
 // Example:
 //
 //	dagger call deploy \
 //	  --source . \
 //	  --compose-path docker/docker-compose.yml \
-//	  --project-name chat
+//	  --project-name chat \
+//	  --health-timeout 300 \
+//	  --strategy rolling
 //
 // +cache="never"
 func (m *DockerCompose) Deploy(
@@ -37,30 +72,109 @@ func (m *DockerCompose) Deploy(
 	composePath string,
 	// +optional
 	projectName string,
+	// +optional
+	// +default=120
+	healthTimeout int,
+	// +optional
+	// +default=true
+	rollback bool,
+	// +optional
+	// +default="recreate"
+	strategy string,
 ) (string, error) {
 	if composePath == "" {
 		composePath = "docker-compose.yml"
 	}
+	if healthTimeout < 0 {
+		return "", fmt.Errorf("healthTimeout must not be negative")
+	}
+	if strategy == "" {
+		strategy = "recreate"
+	}
+	if strategy != "recreate" && strategy != "rolling" {
+		return "", fmt.Errorf("invalid strategy: %s (supported: recreate, rolling)", strategy)
+	}
 
-	container := m.buildContainer(ctx, source, composePath)
+	return m.forEachHost(ctx, "deployment", func(ctx context.Context, host *SSHHost) (string, error) {
+		return m.deployHost(ctx, host, source, composePath, projectName, healthTimeout, rollback, strategy)
+	})
+}
+
+// deployHost deploys the stack to a single host
+func (m *DockerCompose) deployHost(
+	ctx context.Context,
+	host *SSHHost,
+	source *dagger.Directory,
+	composePath string,
+	projectName string,
+	healthTimeout int,
+	rollback bool,
+	strategy string,
+) (string, error) {
+	container := m.buildContainer(ctx, source, composePath, host)
 	composeCmd := getComposeCommand(composePath)
 
-	// Add project name if specified
-	if projectName != "" {
-		composeCmd = append(composeCmd, "-p", projectName)
+	config, err := readComposeConfig(ctx, container, composeCmd)
+	if err != nil {
+		return "", err
+	}
+
+	// The project name identifies the running deployment used for rollback
+	if projectName == "" {
+		projectName = config.Name
+	}
+	composeCmd = append(composeCmd, "-p", projectName)
+
+	var rolling []string
+	if strategy == "rolling" {
+		if rolling, err = rollingServices(config); err != nil {
+			return "", err
+		}
+	}
+
+	// Capture the running deployment before replacing it
+	previous, err := captureDeployment(ctx, container, composeCmd)
+	if err != nil {
+		return "", err
 	}
 
 	// Deploy with force pull and recreate
 	// --pull always: forces re-download of images (ignores local cache)
 	// --force-recreate: recreates containers even if config unchanged
 	//
-	// IMPORTANT: WithEnvVariable with timestamp prevents Dagger from caching
-	// the execution result. Without this, Dagger may return cached output
+	// IMPORTANT: runCommand sets a timestamp variable that prevents Dagger from
+	// caching the execution result. Without this, Dagger may return cached output
 	// without actually running the deployment commands on the remote host.
-	upCmd := append(composeCmd, "up", "-d", "--pull", "always", "--force-recreate")
-	container = container.
-		WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
-		WithExec(upCmd)
+	if len(rolling) > 0 {
+		timeout := time.Duration(healthTimeout) * time.Second
+		if timeout == 0 {
+			timeout = 120 * time.Second
+		}
+		if _, err := deployRolling(ctx, container, composeCmd, config, rolling, timeout); err != nil {
+			return "", deployFailure(ctx, container, composeCmd, projectName, previous, rollback, healthTimeout, err.Error(), nil)
+		}
+	} else {
+		upCmd := append(composeCmd, "up", "-d", "--pull", "always", "--force-recreate")
+		output, exitCode, err := runCommand(ctx, container, upCmd)
+		if err != nil {
+			return "", fmt.Errorf("deployment failed: %w", err)
+		}
+		if exitCode != 0 {
+			failure := fmt.Sprintf("docker compose up exited with code %d\n\n%s", exitCode, output)
+			return "", deployFailure(ctx, container, composeCmd, projectName, previous, rollback, healthTimeout, failure, nil)
+		}
+	}
+
+	if healthTimeout > 0 {
+		services, healthy, err := waitHealthy(ctx, container, composeCmd, time.Duration(healthTimeout)*time.Second)
+		if err != nil {
+			return "", fmt.Errorf("deployment failed: %w", err)
+		}
+		if !healthy {
+			failure := fmt.Sprintf("services did not become healthy within %ds\n\n%s", healthTimeout, formatServices(services))
+			return "", deployFailure(ctx, container, composeCmd, projectName, previous, rollback, healthTimeout, failure, services)
+		}
+	}
 
 	// Get container status
 	psCmd := append(composeCmd, "ps")
@@ -71,3 +185,53 @@ func (m *DockerCompose) Deploy(
 
 	return fmt.Sprintf("Deployment successful\n\n%s", output), nil
 }
+
+// deployFailure collects the logs of failing services, rolls back to the
+// previous images if possible and returns the failure report
+func deployFailure(
+	ctx context.Context,
+	container *dagger.Container,
+	composeCmd []string,
+	projectName string,
+	previous []deployedService,
+	rollback bool,
+	healthTimeout int,
+	failure string,
+	services []composeService,
+) error {
+	report := []string{"deployment failed: " + failure}
+
+	// Logs of the services that are not ready
+	var failing []string
+	for _, s := range services {
+		if !serviceReady(s) && !slices.Contains(failing, s.Service) {
+			failing = append(failing, s.Service)
+		}
+	}
+	if len(failing) > 0 {
+		logsCmd := append(append(composeCmd, "logs", "--no-color", "--tail", "20"), failing...)
+		if logs, _, err := runCommand(ctx, container, logsCmd); err == nil && logs != "" {
+			report = append(report, "Logs of failing services:\n"+logs)
+		}
+	}
+
+	if healthTimeout == 0 {
+		healthTimeout = 120
+	}
+
+	switch {
+	case !rollback:
+		report = append(report, "Rollback disabled: the failed deployment is still in place")
+	case len(previous) == 0:
+		report = append(report, "Rollback skipped: no service of project "+projectName+" was running before the deployment")
+	default:
+		status, err := rollbackTo(ctx, container, composeCmd, previous, time.Duration(healthTimeout)*time.Second)
+		if err != nil {
+			report = append(report, fmt.Sprintf("Rollback failed: %v", err))
+		} else {
+			report = append(report, "Rolled back to the previous images, the new compose configuration was kept\n\n"+status)
+		}
+	}
+
+	return fmt.Errorf("%s", strings.Join(report, "\n\n"))
+}
@@ -11,6 +11,9 @@ import (
 // This function stops all containers and removes them, networks, and volumes
 // created by the Docker Compose stack.
 //
+// With several hosts (see WithContext), runs against each of them and returns
+// a per-host result table.
+//
 // Parameters:
 //   - source: Directory containing the docker-compose.yml file
 //   - composePath: Path to docker-compose.yml relative to source (default: "docker-compose.yml")
@@ -37,20 +40,22 @@ func (m *DockerCompose) Down(
 		composePath = "docker-compose.yml"
 	}
 
-	container := m.buildContainer(ctx, source, composePath)
-	composeCmd := getComposeCommand(composePath)
+	return m.forEachHost(ctx, "down", func(ctx context.Context, host *SSHHost) (string, error) {
+		container := m.buildContainer(ctx, source, composePath, host)
+		composeCmd := getComposeCommand(composePath)
 
-	// Add project name if specified
-	if projectName != "" {
-		composeCmd = append(composeCmd, "-p", projectName)
-	}
+		// Add project name if specified
+		if projectName != "" {
+			composeCmd = append(composeCmd, "-p", projectName)
+		}
 
-	// Stop and remove containers
-	downCmd := append(composeCmd, "down")
-	output, err := container.WithExec(downCmd).Stdout(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to stop containers: %w", err)
-	}
+		// Stop and remove containers
+		downCmd := append(composeCmd, "down")
+		output, err := container.WithExec(downCmd).Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to stop containers: %w", err)
+		}
 
-	return fmt.Sprintf("Containers stopped successfully\n\n%s", output), nil
+		return fmt.Sprintf("Containers stopped successfully\n\n%s", output), nil
+	})
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// hostResult is the outcome of an operation on one host
+type hostResult struct {
+	host    string
+	output  string
+	err     error
+	skipped bool
+}
+
+// forEachHost runs an operation against every configured host, honoring
+// WithParallelism(). Without hosts, the operation runs once against the local
+// Docker context; with a single host, its output and error are returned as is.
+// With several hosts, returns a per-host result table followed by each output,
+// and an error when any host failed.
+func (m *DockerCompose) forEachHost(
+	ctx context.Context,
+	operation string,
+	run func(ctx context.Context, host *SSHHost) (string, error),
+) (string, error) {
+	switch len(m.Hosts) {
+	case 0:
+		return run(ctx, nil)
+	case 1:
+		return run(ctx, m.Hosts[0])
+	}
+
+	parallelism := m.Parallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+
+	results := make([]*hostResult, len(m.Hosts))
+	slots := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+
+	for i, host := range m.Hosts {
+		slots <- struct{}{}
+
+		// Fail-fast: hosts not started yet are skipped after the first failure
+		mu.Lock()
+		skip := failed && !m.ContinueOnError
+		mu.Unlock()
+		if skip {
+			<-slots
+			results[i] = &hostResult{host: hostLabel(host), skipped: true}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			output, err := run(ctx, host)
+			mu.Lock()
+			failed = failed || err != nil
+			mu.Unlock()
+			results[i] = &hostResult{host: hostLabel(host), output: output, err: err}
+		}()
+	}
+	wg.Wait()
+
+	report, failures := formatHostResults(results)
+	if failures > 0 {
+		return "", fmt.Errorf("%s failed on %d of %d hosts\n\n%s", operation, failures, len(results), report)
+	}
+	return report, nil
+}
+
+// formatHostResults renders a result table followed by the output of each host,
+// and returns the number of failed hosts
+func formatHostResults(results []*hostResult) (string, int) {
+	failures := 0
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tRESULT")
+	for _, r := range results {
+		result := "ok"
+		switch {
+		case r.skipped:
+			result = "skipped"
+		case r.err != nil:
+			failures++
+			summary, _, _ := strings.Cut(r.err.Error(), "\n")
+			result = "failed: " + summary
+		}
+		fmt.Fprintf(w, "%s\t%s\n", r.host, result)
+	}
+	w.Flush()
+
+	for _, r := range results {
+		switch {
+		case r.skipped:
+			continue
+		case r.err != nil:
+			fmt.Fprintf(&b, "\n=== %s ===\n%v\n", r.host, r.err)
+		default:
+			fmt.Fprintf(&b, "\n=== %s ===\n%s\n", r.host, strings.TrimRight(r.output, "\n"))
+		}
+	}
+
+	return b.String(), failures
+}
+
+// hostLabel returns user@host, with the port when it is not 22
+func hostLabel(host *SSHHost) string {
+	if host.Port != 22 {
+		return fmt.Sprintf("%s@%s:%d", host.User, host.Host, host.Port)
+	}
+	return fmt.Sprintf("%s@%s", host.User, host.Host)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"dagger/docker-compose/internal/dagger"
+)
+
+// healthPollInterval is the delay between two service status checks
+const healthPollInterval = 5 * time.Second
+
+// composeService is a container reported by docker compose ps
+type composeService struct {
+	ID       string
+	Name     string
+	Service  string
+	State    string
+	Health   string
+	ExitCode int
+}
+
+// listServices returns every container of the compose project
+func listServices(ctx context.Context, container *dagger.Container, composeCmd []string) ([]composeService, error) {
+	psCmd := append(composeCmd, "ps", "--all", "--format", "json")
+	output, err := container.
+		WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
+		WithExec(psCmd).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	// Compose prints a JSON array (before v2.21) or one JSON object per line
+	var services []composeService
+	output = strings.TrimSpace(output)
+	if strings.HasPrefix(output, "[") {
+		if err := json.Unmarshal([]byte(output), &services); err != nil {
+			return nil, fmt.Errorf("failed to parse status: %w", err)
+		}
+		return services, nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var s composeService
+		if err := json.Unmarshal([]byte(line), &s); err != nil {
+			return nil, fmt.Errorf("failed to parse status: %w", err)
+		}
+		services = append(services, s)
+	}
+	return services, nil
+}
+
+// serviceReady reports whether a container is up: healthy when it has a
+// healthcheck, running otherwise. One-shot containers that exited with code 0
+// (e.g., migrations) are ready too.
+func serviceReady(s composeService) bool {
+	switch s.State {
+	case "running":
+		return s.Health == "" || s.Health == "healthy"
+	case "exited":
+		return s.ExitCode == 0
+	default:
+		return false
+	}
+}
+
+// serviceFailed reports whether a container can no longer become ready
+func serviceFailed(s composeService) bool {
+	return s.Health == "unhealthy" || s.State == "dead" || (s.State == "exited" && s.ExitCode != 0)
+}
+
+// waitHealthy polls the compose project until every container is ready, a
+// container fails, or the timeout expires. Returns the last observed state and
+// whether every container is ready.
+func waitHealthy(
+	ctx context.Context,
+	container *dagger.Container,
+	composeCmd []string,
+	timeout time.Duration,
+) ([]composeService, bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		services, err := listServices(ctx, container, composeCmd)
+		if err != nil {
+			return nil, false, err
+		}
+
+		ready := len(services) > 0
+		failed := false
+		for _, s := range services {
+			ready = ready && serviceReady(s)
+			failed = failed || serviceFailed(s)
+		}
+		if ready {
+			return services, true, nil
+		}
+		if failed || time.Now().After(deadline) {
+			return services, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
+
+// formatServices renders containers as a table
+func formatServices(services []composeService) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tCONTAINER\tSTATE\tHEALTH")
+	for _, s := range services {
+		state := s.State
+		if s.State == "exited" {
+			state = fmt.Sprintf("exited (%d)", s.ExitCode)
+		}
+		health := s.Health
+		if health == "" {
+			health = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Service, s.Name, state, health)
+	}
+	w.Flush()
+	return b.String()
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"dagger/docker-compose/internal/dagger"
@@ -13,6 +16,8 @@ func (m *DockerCompose) buildContainer(
 	ctx context.Context,
 	source *dagger.Directory,
 	composePath string,
+	// Remote host (nil for the local Docker context)
+	host *SSHHost,
 ) *dagger.Container {
 	// Start with Docker CLI image
 	container := dag.Container().
@@ -21,32 +26,36 @@ func (m *DockerCompose) buildContainer(
 		WithWorkdir("/workspace")
 
 	// Configure SSH context if provided, otherwise use local socket
-	if m.SSHHost != "" && m.SSHKey != nil {
+	if host != nil && host.Key != nil {
 		// Install Docker Compose and SSH client for remote deployment
 		container = container.WithExec([]string{
 			"sh", "-c",
-			"apk add --no-cache docker-cli-compose openssh-client",
+			"apk add --no-cache docker-cli-compose openssh-client openssh-keygen",
 		})
 
 		// Setup SSH directory and key
 		// Mount secret to temp location, then copy with correct permissions (mounted secrets are read-only)
 		container = container.
 			WithExec([]string{"mkdir", "-p", "/root/.ssh"}).
-			WithMountedSecret("/tmp/ssh_key", m.SSHKey).
+			WithMountedSecret("/tmp/ssh_key", host.Key).
 			WithExec([]string{"sh", "-c", "cp /tmp/ssh_key /root/.ssh/id_ed25519 && chmod 600 /root/.ssh/id_ed25519"})
 
-		// Configure SSH to skip host key verification (for CI/CD)
-		sshConfig := "Host *\n  StrictHostKeyChecking no\n  UserKnownHostsFile /dev/null\n  LogLevel ERROR\n"
-		container = container.WithNewFile("/root/.ssh/config", sshConfig, dagger.ContainerWithNewFileOpts{
+		// Jump host key, mounted like the host key
+		if host.JumpHost != "" {
+			container = container.
+				WithMountedSecret("/tmp/ssh_jump_key", host.JumpKey).
+				WithExec([]string{"sh", "-c", "cp /tmp/ssh_jump_key /root/.ssh/id_jump && chmod 600 /root/.ssh/id_jump"})
+		}
+
+		container = container.WithNewFile("/root/.ssh/config", sshConfig(host), dagger.ContainerWithNewFileOpts{
 			Permissions: 0o600,
 		})
 
+		// Verify the host key unless verification was explicitly disabled
+		container = withKnownHosts(container, host)
+
 		// Set DOCKER_HOST to SSH endpoint
-		sshHost := fmt.Sprintf("ssh://%s@%s", m.SSHUser, m.SSHHost)
-		if m.SSHPort != 22 {
-			sshHost = fmt.Sprintf("ssh://%s@%s:%d", m.SSHUser, m.SSHHost, m.SSHPort)
-		}
-		container = container.WithEnvVariable("DOCKER_HOST", sshHost)
+		container = container.WithEnvVariable("DOCKER_HOST", "ssh://"+hostLabel(host))
 	} else {
 		// Local Docker socket not supported in current Dagger SDK
 		// SSH context is required for docker-compose deployments
@@ -89,6 +98,81 @@ func (m *DockerCompose) buildContainer(
 	return container
 }
 
+// jumpHostAlias is the SSH config entry of the jump host
+const jumpHostAlias = "dagger-jump"
+
+// sshConfig returns the SSH client configuration for a host: strict host key
+// checking unless explicitly disabled, and a ProxyJump through the jump host
+// if one is configured
+func sshConfig(host *SSHHost) string {
+	var config strings.Builder
+	if host.JumpHost != "" {
+		fmt.Fprintf(&config, "Host %s\n  HostName %s\n  User %s\n  Port %d\n  IdentityFile /root/.ssh/id_jump\n\n",
+			jumpHostAlias, host.JumpHost, host.JumpUser, host.JumpPort)
+		fmt.Fprintf(&config, "Host %s\n  ProxyJump %s\n\n", host.Host, jumpHostAlias)
+	}
+	if host.InsecureIgnoreHostKey {
+		config.WriteString("Host *\n  StrictHostKeyChecking no\n  UserKnownHostsFile /dev/null\n  LogLevel ERROR\n")
+	} else {
+		config.WriteString("Host *\n  StrictHostKeyChecking yes\n  UserKnownHostsFile /root/.ssh/known_hosts\n  LogLevel ERROR\n")
+	}
+	return config.String()
+}
+
+// hostKeyScript appends the scanned host keys matching a pinned fingerprint to
+// known_hosts and fails unless this scan found one. SSH_KEYSCAN overrides the
+// scan command, e.g., to scan a host through its jump host.
+const hostKeyScript = `set -e
+${SSH_KEYSCAN:-ssh-keyscan} -p "$SSH_PORT" "$SSH_HOST" 2>/dev/null > /tmp/host_keys || true
+found=0
+while read -r line; do
+  case "$line" in "#"*|"") continue ;; esac
+  echo "$line" > /tmp/host_key
+  if [ "$(ssh-keygen -lf /tmp/host_key | awk '{print $2}')" = "$SSH_FINGERPRINT" ]; then
+    echo "$line" >> /root/.ssh/known_hosts
+    found=1
+  fi
+done < /tmp/host_keys
+rm -f /tmp/host_keys /tmp/host_key
+if [ "$found" = 0 ]; then
+  echo "no host key of $SSH_HOST:$SSH_PORT matches $SSH_FINGERPRINT" >&2
+  exit 1
+fi
+chmod 600 /root/.ssh/known_hosts
+`
+
+// withKnownHosts installs /root/.ssh/known_hosts from the host's known_hosts
+// file or pinned fingerprints. Behind a jump host, the jump host key is verified
+// first and the host is scanned through it, since it is not reachable directly.
+func withKnownHosts(container *dagger.Container, host *SSHHost) *dagger.Container {
+	switch {
+	case host.KnownHosts != nil:
+		return container.WithFile("/root/.ssh/known_hosts", host.KnownHosts, dagger.ContainerWithFileOpts{
+			Permissions: 0o600,
+		})
+	case host.HostKeyFingerprint != "":
+		// Host keys must be scanned on every run
+		container = container.WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String())
+		keyscan := "ssh-keyscan"
+		if host.JumpHost != "" {
+			container = container.
+				WithEnvVariable("SSH_HOST", host.JumpHost).
+				WithEnvVariable("SSH_PORT", strconv.Itoa(host.JumpPort)).
+				WithEnvVariable("SSH_FINGERPRINT", host.JumpHostKeyFingerprint).
+				WithExec([]string{"sh", "-c", hostKeyScript})
+			keyscan = "ssh " + jumpHostAlias + " ssh-keyscan"
+		}
+		return container.
+			WithEnvVariable("SSH_KEYSCAN", keyscan).
+			WithEnvVariable("SSH_HOST", host.Host).
+			WithEnvVariable("SSH_PORT", strconv.Itoa(host.Port)).
+			WithEnvVariable("SSH_FINGERPRINT", host.HostKeyFingerprint).
+			WithExec([]string{"sh", "-c", hostKeyScript})
+	default:
+		return container
+	}
+}
+
 // getComposeCommand returns the docker compose command with the compose file path
 func getComposeCommand(composePath string) []string {
 	if composePath == "" {
@@ -96,3 +180,64 @@ func getComposeCommand(composePath string) []string {
 	}
 	return []string{"docker", "compose", "-f", composePath}
 }
+
+// runCommand runs a command without failing on a non-zero exit code and
+// returns its combined output and exit code
+func runCommand(ctx context.Context, container *dagger.Container, args []string) (string, int, error) {
+	exec := container.
+		WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
+		WithExec(args, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+	exitCode, err := exec.ExitCode(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	stdout, err := exec.Stdout(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	stderr, err := exec.Stderr(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	return strings.TrimSpace(stdout + stderr), exitCode, nil
+}
+
+// commandStdout runs a command that must succeed and returns its standard output
+// only, so that warnings written to stderr cannot corrupt parsed output
+func commandStdout(ctx context.Context, container *dagger.Container, args []string) (string, error) {
+	return container.
+		WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
+		WithExec(args).
+		Stdout(ctx)
+}
+
+// composeConfig is the subset of the rendered compose config used by Deploy
+type composeConfig struct {
+	Name     string                          `json:"name"`
+	Services map[string]composeServiceConfig `json:"services"`
+}
+
+// composeServiceConfig is the subset of a rendered service definition used by Deploy
+type composeServiceConfig struct {
+	ContainerName string            `json:"container_name"`
+	Labels        map[string]string `json:"labels"`
+	Ports         []struct {
+		Published any `json:"published"`
+	} `json:"ports"`
+}
+
+// readComposeConfig renders the compose files with docker compose config
+func readComposeConfig(ctx context.Context, container *dagger.Container, composeCmd []string) (*composeConfig, error) {
+	configCmd := append(composeCmd, "config", "--format", "json")
+	output, err := container.WithExec(configCmd).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render compose config: %w", err)
+	}
+
+	var config composeConfig
+	if err := json.Unmarshal([]byte(output), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse compose config: %w", err)
+	}
+	return &config, nil
+}
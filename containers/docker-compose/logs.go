@@ -10,6 +10,9 @@ import (
 //
 // This function fetches the logs from all containers in the Docker Compose stack.
 //
+// With several hosts (see WithContext), runs against each of them and returns
+// a per-host result table.
+//
 // Parameters:
 //   - source: Directory containing the docker-compose.yml file
 //   - composePath: Path to docker-compose.yml relative to source (default: "docker-compose.yml")
@@ -44,20 +47,22 @@ func (m *DockerCompose) Logs(
 		tail = 100
 	}
 
-	container := m.buildContainer(ctx, source, composePath)
-	composeCmd := getComposeCommand(composePath)
+	return m.forEachHost(ctx, "logs", func(ctx context.Context, host *SSHHost) (string, error) {
+		container := m.buildContainer(ctx, source, composePath, host)
+		composeCmd := getComposeCommand(composePath)
 
-	// Add project name if specified
-	if projectName != "" {
-		composeCmd = append(composeCmd, "-p", projectName)
-	}
+		// Add project name if specified
+		if projectName != "" {
+			composeCmd = append(composeCmd, "-p", projectName)
+		}
 
-	// Get logs
-	logsCmd := append(composeCmd, "logs", "--tail", fmt.Sprintf("%d", tail))
-	output, err := container.WithExec(logsCmd).Stdout(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to retrieve logs: %w", err)
-	}
+		// Get logs
+		logsCmd := append(composeCmd, "logs", "--tail", fmt.Sprintf("%d", tail))
+		output, err := container.WithExec(logsCmd).Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to retrieve logs: %w", err)
+		}
 
-	return output, nil
+		return output, nil
+	})
 }
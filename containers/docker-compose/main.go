@@ -6,7 +6,8 @@
 // Example usage:
 //
 //	dagger call -m containers/docker-compose \
-//	  with-context --host 172.16.24.97 --user admin --ssh-key env:SSH_KEY \
+//	  with-context --host 172.16.24.97 --user admin --ssh-key env:SSH_KEY --known-hosts ~/.ssh/known_hosts \
+//	  with-context --host 172.16.24.98 --user admin --ssh-key env:SSH_KEY --known-hosts ~/.ssh/known_hosts \
 //	  with-registry --host registry.example.com --username env:USER --password env:PASS \
 //	  with-secret --key DB_PASSWORD --value env:DB_PASSWORD \
 //	  with-variable --key IMAGE_TAG --value v1.0.0 \
@@ -28,11 +29,13 @@ type DockerCompose struct {
 	// Environment variables to inject
 	Variables []*Variable
 
-	// SSH Context configuration for remote deployment
-	SSHHost string
-	SSHUser string
-	SSHPort int
-	SSHKey  *dagger.Secret
+	// SSH Context configuration for remote deployment, one entry per host
+	Hosts []*SSHHost
+
+	// Fleet execution: hosts handled at the same time, and whether the
+	// remaining hosts are still handled after a failure
+	Parallelism     int
+	ContinueOnError bool
 
 	// Environment file
 	EnvFile *dagger.File
@@ -45,10 +48,32 @@ type Variable struct {
 	Secret *dagger.Secret
 }
 
+// SSHHost represents a remote Docker host reached over SSH
+type SSHHost struct {
+	Host string
+	User string
+	Port int
+	Key  *dagger.Secret
+
+	// Host key verification: known_hosts file or pinned fingerprint, unless
+	// verification is explicitly disabled
+	KnownHosts            *dagger.File
+	HostKeyFingerprint    string
+	InsecureIgnoreHostKey bool
+
+	// Optional jump host (bastion) the host is reached through
+	JumpHost               string
+	JumpUser               string
+	JumpPort               int
+	JumpKey                *dagger.Secret
+	JumpHostKeyFingerprint string
+}
+
 // New creates a new DockerCompose instance
 func New() *DockerCompose {
 	return &DockerCompose{
-		Variables: []*Variable{},
-		SSHPort:   22,
+		Variables:   []*Variable{},
+		Hosts:       []*SSHHost{},
+		Parallelism: 1,
 	}
 }
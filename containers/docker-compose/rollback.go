@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"dagger/docker-compose/internal/dagger"
+)
+
+// rollbackOverride is the compose override file pinning services to their previous images
+const rollbackOverride = "/tmp/rollback.json"
+
+// deployedService is a running service captured before a deploy
+type deployedService struct {
+	Service string
+	// Image ID the service runs (e.g., "sha256:...")
+	Image string
+	// Compose configuration hash (label com.docker.compose.config-hash)
+	ConfigHash string
+}
+
+// captureDeployment returns the services of a project that are running on the
+// Docker host, with the image and configuration hash of each, so a failed
+// deploy can be rolled back to them
+func captureDeployment(ctx context.Context, container *dagger.Container, composeCmd []string) ([]deployedService, error) {
+	services, err := listServices(ctx, container, composeCmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, s := range services {
+		if s.State == "running" {
+			ids = append(ids, s.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	output, err := commandStdout(ctx, container, append([]string{"docker", "inspect"}, ids...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect running containers: %w", err)
+	}
+
+	var containers []struct {
+		Image  string
+		Config struct {
+			Labels map[string]string
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse running containers: %w", err)
+	}
+
+	// Replicas of a service share the same image and configuration
+	var deployed []deployedService
+	for _, c := range containers {
+		service := c.Config.Labels["com.docker.compose.service"]
+		if service == "" || slices.ContainsFunc(deployed, func(d deployedService) bool { return d.Service == service }) {
+			continue
+		}
+		deployed = append(deployed, deployedService{
+			Service:    service,
+			Image:      c.Image,
+			ConfigHash: c.Config.Labels["com.docker.compose.config-hash"],
+		})
+	}
+	sort.Slice(deployed, func(i, j int) bool { return deployed[i].Service < deployed[j].Service })
+	return deployed, nil
+}
+
+// rollbackTo restores the images (not the configuration) of the services
+// captured by captureDeployment and waits for them to become healthy
+//
+// The compose files of the failed deploy are reused with an override pinning
+// every service to its previous image, so secrets are injected the same way as
+// during the deploy and are never persisted. Services added by the failed
+// deploy are removed.
+func rollbackTo(
+	ctx context.Context,
+	container *dagger.Container,
+	composeCmd []string,
+	previous []deployedService,
+	timeout time.Duration,
+) (string, error) {
+	override := map[string]map[string]any{}
+	var names []string
+	for _, s := range previous {
+		override[s.Service] = map[string]any{"image": s.Image, "pull_policy": "never"}
+		names = append(names, s.Service)
+	}
+	overrideJSON, err := json.Marshal(map[string]any{"services": override})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode rollback override: %w", err)
+	}
+
+	// Configuration hashes of the failed deploy, one "service hash" per line
+	hashes, err := commandStdout(ctx, container, append(composeCmd, "config", "--hash", "*"))
+	if err != nil {
+		return "", fmt.Errorf("failed to hash compose config: %w", err)
+	}
+	var added []string
+	for _, line := range strings.Split(strings.TrimSpace(hashes), "\n") {
+		service, _, found := strings.Cut(line, " ")
+		if found && !slices.Contains(names, service) {
+			added = append(added, service)
+		}
+	}
+
+	container = container.WithNewFile(rollbackOverride, string(overrideJSON))
+	rollbackCmd := append(append([]string{}, composeCmd...), "-f", rollbackOverride)
+
+	upCmd := append(append(rollbackCmd, "up", "-d", "--force-recreate", "--no-deps"), names...)
+	output, exitCode, err := runCommand(ctx, container, upCmd)
+	if err != nil {
+		return "", err
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("docker compose up exited with code %d:\n%s", exitCode, output)
+	}
+
+	if len(added) > 0 {
+		rmCmd := append(append(rollbackCmd, "rm", "--stop", "--force"), added...)
+		if output, exitCode, err := runCommand(ctx, container, rmCmd); err != nil || exitCode != 0 {
+			return "", commandError("docker compose rm", output, exitCode, err)
+		}
+	}
+
+	services, healthy, err := waitHealthy(ctx, container, rollbackCmd, timeout)
+	if err != nil {
+		return "", err
+	}
+	if !healthy {
+		return "", fmt.Errorf("previous deployment did not become healthy\n\n%s", formatServices(services))
+	}
+
+	status := formatServices(services)
+	if changed := changedServices(previous, hashes); len(changed) > 0 {
+		status = fmt.Sprintf("Only images were restored: the compose configuration of %s differs from the previous deployment and was not rolled back\n\n%s",
+			strings.Join(changed, ", "), status)
+	}
+	return status, nil
+}
+
+// changedServices returns the captured services whose configuration hash (which
+// covers the image reference) differs from the one of the failed deploy
+func changedServices(previous []deployedService, hashes string) []string {
+	rendered := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(hashes), "\n") {
+		if service, hash, found := strings.Cut(line, " "); found {
+			rendered[service] = strings.TrimSpace(hash)
+		}
+	}
+
+	var changed []string
+	for _, s := range previous {
+		if s.ConfigHash != "" && rendered[s.Service] != "" && rendered[s.Service] != s.ConfigHash {
+			changed = append(changed, s.Service)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChangedServices(t *testing.T) {
+	previous := []deployedService{
+		{Service: "api", ConfigHash: "hash-api"},
+		{Service: "web", ConfigHash: "hash-web"},
+	}
+	// api changed, web did not, db was added by the failed deploy
+	hashes := "api hash-api-2\nweb hash-web\ndb hash-db\n"
+
+	if got := changedServices(previous, hashes); !slices.Equal(got, []string{"api"}) {
+		t.Errorf("changedServices() = %v, want [api]", got)
+	}
+}
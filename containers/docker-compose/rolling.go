@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/docker-compose/internal/dagger"
+)
+
+// rollingLabel is the service label selecting the rolling update strategy
+const rollingLabel = "dagger.deploy.strategy"
+
+// rollingServices returns the services declaring the rolling update strategy
+// (label dagger.deploy.strategy=rolling), sorted by name
+func rollingServices(config *composeConfig) ([]string, error) {
+	var services []string
+	for name, service := range config.Services {
+		if service.Labels[rollingLabel] != "rolling" {
+			continue
+		}
+
+		// Old and new replicas run side by side during the update
+		if service.ContainerName != "" {
+			return nil, fmt.Errorf("service %s cannot use the rolling strategy: container_name prevents running two replicas", name)
+		}
+		for _, port := range service.Ports {
+			if published := fmt.Sprint(port.Published); port.Published != nil && published != "" && published != "0" {
+				return nil, fmt.Errorf("service %s cannot use the rolling strategy: published port %s prevents running two replicas", name, published)
+			}
+		}
+
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// deployRolling deploys the stack, updating rolling services one at a time and
+// recreating the others
+func deployRolling(
+	ctx context.Context,
+	container *dagger.Container,
+	composeCmd []string,
+	config *composeConfig,
+	rolling []string,
+	timeout time.Duration,
+) (string, error) {
+	var recreate []string
+	for name := range config.Services {
+		if !slices.Contains(rolling, name) {
+			recreate = append(recreate, name)
+		}
+	}
+	sort.Strings(recreate)
+
+	var outputs []string
+
+	// Recreate the other services first, rolling services usually depend on them
+	if len(recreate) > 0 {
+		upCmd := append(append(composeCmd, "up", "-d", "--pull", "always", "--force-recreate", "--no-deps"), recreate...)
+		output, exitCode, err := runCommand(ctx, container, upCmd)
+		if err != nil {
+			return "", err
+		}
+		if exitCode != 0 {
+			return "", fmt.Errorf("docker compose up exited with code %d\n\n%s", exitCode, output)
+		}
+		outputs = append(outputs, output)
+	}
+
+	for _, service := range rolling {
+		output, err := rollingUpdate(ctx, container, composeCmd, service, timeout)
+		if err != nil {
+			return "", fmt.Errorf("rolling update of %s failed: %w", service, err)
+		}
+		outputs = append(outputs, output)
+	}
+
+	return strings.Join(outputs, "\n"), nil
+}
+
+// rollingUpdate replaces the containers of a service without downtime: new
+// replicas are started next to the old ones, and the old ones are removed once
+// the new ones are ready. New replicas are removed if they do not become ready.
+func rollingUpdate(
+	ctx context.Context,
+	container *dagger.Container,
+	composeCmd []string,
+	service string,
+	timeout time.Duration,
+) (string, error) {
+	pullCmd := append(composeCmd, "pull", service)
+	if output, exitCode, err := runCommand(ctx, container, pullCmd); err != nil || exitCode != 0 {
+		return "", commandError("docker compose pull", output, exitCode, err)
+	}
+
+	oldIDs, err := serviceContainerIDs(ctx, container, composeCmd, service)
+	if err != nil {
+		return "", err
+	}
+
+	// First deployment of the service: nothing to replace
+	if len(oldIDs) == 0 {
+		upCmd := append(composeCmd, "up", "-d", "--no-deps", service)
+		output, exitCode, err := runCommand(ctx, container, upCmd)
+		if err != nil || exitCode != 0 {
+			return "", commandError("docker compose up", output, exitCode, err)
+		}
+		return output, nil
+	}
+
+	// Scale up: one new replica per old one, old replicas are kept as they are
+	replicas := len(oldIDs)
+	scaleCmd := append(composeCmd, "up", "-d", "--no-deps", "--no-recreate",
+		"--scale", fmt.Sprintf("%s=%d", service, 2*replicas), service)
+	output, exitCode, err := runCommand(ctx, container, scaleCmd)
+	if err != nil || exitCode != 0 {
+		return "", commandError("docker compose up --scale", output, exitCode, err)
+	}
+
+	allIDs, err := serviceContainerIDs(ctx, container, composeCmd, service)
+	if err != nil {
+		return "", err
+	}
+	var newIDs []string
+	for _, id := range allIDs {
+		if !slices.Contains(oldIDs, id) {
+			newIDs = append(newIDs, id)
+		}
+	}
+
+	if len(newIDs) == 0 {
+		return "", fmt.Errorf("no new replica was started\n\n%s", output)
+	}
+
+	// Wait for the new replicas, then remove whichever set must go
+	status, ready, err := waitContainersReady(ctx, container, newIDs, timeout)
+	if err != nil {
+		return "", err
+	}
+	if !ready {
+		_ = removeContainers(ctx, container, newIDs)
+		return "", fmt.Errorf("new replicas did not become healthy within %s, kept the old ones\n\n%s", timeout, status)
+	}
+	if err := removeContainers(ctx, container, oldIDs); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s: replaced %d replica(s)", service, replicas), nil
+}
+
+// serviceContainerIDs returns the IDs of the containers of a service
+func serviceContainerIDs(ctx context.Context, container *dagger.Container, composeCmd []string, service string) ([]string, error) {
+	psCmd := append(composeCmd, "ps", "--quiet", "--no-trunc", service)
+	// Only parse stdout: compose warnings on stderr are not container IDs
+	output, err := commandStdout(ctx, container, psCmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers of %s: %w", service, err)
+	}
+	return strings.Fields(output), nil
+}
+
+// waitContainersReady polls containers until all are ready (see serviceReady),
+// one fails, or the timeout expires. Returns the last observed status.
+func waitContainersReady(
+	ctx context.Context,
+	container *dagger.Container,
+	ids []string,
+	timeout time.Duration,
+) (string, bool, error) {
+	inspectCmd := append([]string{"docker", "inspect", "--format",
+		"{{.Name}} {{.State.Status}} {{.State.ExitCode}} {{if .State.Health}}{{.State.Health.Status}}{{end}}"}, ids...)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		output, exitCode, err := runCommand(ctx, container, inspectCmd)
+		if err != nil || exitCode != 0 {
+			return "", false, commandError("docker inspect", output, exitCode, err)
+		}
+
+		ready := true
+		failed := false
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 {
+				continue
+			}
+			s := composeService{Name: strings.TrimPrefix(fields[0], "/"), State: fields[1]}
+			s.ExitCode, _ = strconv.Atoi(fields[2])
+			if len(fields) > 3 {
+				s.Health = fields[3]
+			}
+			// One-shot containers are not expected in a rolling service
+			ready = ready && s.State == "running" && serviceReady(s)
+			failed = failed || serviceFailed(s)
+		}
+		if ready {
+			return output, true, nil
+		}
+		if failed || time.Now().After(deadline) {
+			return output, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", false, ctx.Err()
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
+
+// removeContainers gracefully stops and removes containers
+func removeContainers(ctx context.Context, container *dagger.Container, ids []string) error {
+	for _, command := range []string{"stop", "rm"} {
+		cmd := append([]string{"docker", command}, ids...)
+		output, exitCode, err := runCommand(ctx, container, cmd)
+		if err != nil || exitCode != 0 {
+			return commandError("docker "+command, output, exitCode, err)
+		}
+	}
+	return nil
+}
+
+// commandError describes a failed command run with runCommand
+func commandError(command, output string, exitCode int, err error) error {
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", command, err)
+	}
+	return fmt.Errorf("%s exited with code %d\n\n%s", command, exitCode, output)
+}
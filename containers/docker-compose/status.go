@@ -11,6 +11,9 @@ import (
 // This function shows the current state of all containers in the Docker Compose stack,
 // including their names, status, ports, and health status.
 //
+// With several hosts (see WithContext), runs against each of them and returns
+// a per-host result table.
+//
 // Parameters:
 //   - source: Directory containing the docker-compose.yml file
 //   - composePath: Path to docker-compose.yml relative to source (default: "docker-compose.yml")
@@ -37,20 +40,22 @@ func (m *DockerCompose) Status(
 		composePath = "docker-compose.yml"
 	}
 
-	container := m.buildContainer(ctx, source, composePath)
-	composeCmd := getComposeCommand(composePath)
+	return m.forEachHost(ctx, "status", func(ctx context.Context, host *SSHHost) (string, error) {
+		container := m.buildContainer(ctx, source, composePath, host)
+		composeCmd := getComposeCommand(composePath)
 
-	// Add project name if specified
-	if projectName != "" {
-		composeCmd = append(composeCmd, "-p", projectName)
-	}
+		// Add project name if specified
+		if projectName != "" {
+			composeCmd = append(composeCmd, "-p", projectName)
+		}
 
-	// Get container status
-	psCmd := append(composeCmd, "ps")
-	output, err := container.WithExec(psCmd).Stdout(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get status: %w", err)
-	}
+		// Get container status
+		psCmd := append(composeCmd, "ps")
+		output, err := container.WithExec(psCmd).Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get status: %w", err)
+		}
 
-	return output, nil
+		return output, nil
+	})
 }
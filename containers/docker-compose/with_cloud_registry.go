@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"dagger/docker-compose/internal/dagger"
+)
+
+// WithAcrRegistry configures Azure Container Registry authentication with Azure AD
+//
+// The service principal secret or federated token is exchanged for an ACR
+// refresh token by the docker module, which also provides the registry login.
+//
+// Parameters:
+//   - host: ACR hostname (e.g., "myregistry.azurecr.io"); an http(s):// prefix overrides the exchange endpoint
+//   - tenantId: Azure AD tenant ID
+//   - clientId: Service principal or managed identity client ID
+//   - clientSecret: Service principal client secret (optional)
+//   - federatedToken: Federated token used instead of a client secret (optional)
+//   - authorityHost: Azure AD authority host (default: "https://login.microsoftonline.com")
+//
+// Example:
+//
+//	dagger call with-acr-registry \
+//	  --host myregistry.azurecr.io \
+//	  --tenant-id env:AZURE_TENANT_ID \
+//	  --client-id env:AZURE_CLIENT_ID \
+//	  --client-secret env:AZURE_CLIENT_SECRET
+func (m *DockerCompose) WithAcrRegistry(
+	ctx context.Context,
+	host string,
+	tenantId string,
+	clientId string,
+	// +optional
+	clientSecret *dagger.Secret,
+	// +optional
+	federatedToken *dagger.Secret,
+	// +optional
+	// +default="https://login.microsoftonline.com"
+	authorityHost string,
+) (*DockerCompose, error) {
+	docker := dag.Docker().WithAcrRegistry(host, tenantId, clientId, dagger.DockerWithAcrRegistryOpts{
+		ClientSecret:   clientSecret,
+		FederatedToken: federatedToken,
+		AuthorityHost:  authorityHost,
+	})
+	return m.withDockerRegistry(ctx, docker)
+}
+
+// WithEcrRegistry configures Amazon ECR authentication with AWS credentials
+//
+// The access key is exchanged for an ECR password (like "aws ecr get-login-password")
+// by the docker module, which also provides the registry login.
+//
+// Parameters:
+//   - host: ECR hostname (e.g., "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+//   - accessKeyId: AWS access key ID
+//   - secretAccessKey: AWS secret access key
+//   - sessionToken: AWS session token for temporary credentials (optional)
+//   - region: AWS region (optional, defaults to the region in the hostname)
+//   - endpoint: ECR API endpoint override (optional)
+//
+// Example:
+//
+//	dagger call with-ecr-registry \
+//	  --host 123456789012.dkr.ecr.eu-west-1.amazonaws.com \
+//	  --access-key-id env:AWS_ACCESS_KEY_ID \
+//	  --secret-access-key env:AWS_SECRET_ACCESS_KEY
+func (m *DockerCompose) WithEcrRegistry(
+	ctx context.Context,
+	host string,
+	accessKeyId *dagger.Secret,
+	secretAccessKey *dagger.Secret,
+	// +optional
+	sessionToken *dagger.Secret,
+	// +optional
+	region string,
+	// +optional
+	endpoint string,
+) (*DockerCompose, error) {
+	docker := dag.Docker().WithEcrRegistry(host, accessKeyId, secretAccessKey, dagger.DockerWithEcrRegistryOpts{
+		SessionToken: sessionToken,
+		Region:       region,
+		Endpoint:     endpoint,
+	})
+	return m.withDockerRegistry(ctx, docker)
+}
+
+// WithGcrRegistry configures Google Container/Artifact Registry authentication
+//
+// The service account key is exchanged for an OAuth access token by the
+// docker module, which also provides the registry login.
+//
+// Parameters:
+//   - host: Registry hostname (e.g., "gcr.io", "europe-docker.pkg.dev")
+//   - serviceAccountKey: Service account key (JSON)
+//   - tokenEndpoint: OAuth token endpoint override (optional)
+//
+// Example:
+//
+//	dagger call with-gcr-registry \
+//	  --host europe-docker.pkg.dev \
+//	  --service-account-key file:./sa-key.json
+func (m *DockerCompose) WithGcrRegistry(
+	ctx context.Context,
+	host string,
+	serviceAccountKey *dagger.Secret,
+	// +optional
+	tokenEndpoint string,
+) (*DockerCompose, error) {
+	docker := dag.Docker().WithGcrRegistry(host, serviceAccountKey, dagger.DockerWithGcrRegistryOpts{
+		TokenEndpoint: tokenEndpoint,
+	})
+	return m.withDockerRegistry(ctx, docker)
+}
+
+// withDockerRegistry uses the registry login configured on a docker module instance
+//
+// The docker module strips the URL scheme from the host and sets the username
+// expected by the cloud provider.
+func (m *DockerCompose) withDockerRegistry(ctx context.Context, docker *dagger.Docker) (*DockerCompose, error) {
+	registries, err := docker.Registries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to the registry: %w", err)
+	}
+	if len(registries) != 1 {
+		return nil, fmt.Errorf("expected one registry login, got %d", len(registries))
+	}
+
+	host, err := registries[0].Host(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the registry host: %w", err)
+	}
+	username, err := registries[0].Username(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the registry username: %w", err)
+	}
+	return m.WithRegistry(host, username, registries[0].Password()), nil
+}
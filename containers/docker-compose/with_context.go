@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"dagger/docker-compose/internal/dagger"
 )
 
@@ -8,12 +11,33 @@ import (
 //
 // This enables deployment to remote Docker hosts via SSH instead of local socket.
 // When configured, all Docker commands will be executed on the remote host.
+// Call it several times to target a fleet of hosts: Deploy, Status, Down and
+// Logs then run against every host (see WithParallelism). Configuring the same
+// host and port again replaces its previous settings.
+//
+// The host key is verified against a known_hosts file or a pinned fingerprint
+// (as printed by ssh-keygen -lf, e.g., "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s").
+// Verification can only be disabled explicitly with insecureIgnoreHostKey.
+//
+// Hosts that are only reachable through a bastion are configured with jumpHost:
+// the connection is proxied with the ProxyJump option of the generated SSH config.
+// The jump host key is verified the same way as the host key: the known_hosts
+// file must contain both hosts, and a pinned hostKeyFingerprint requires
+// jumpHostKeyFingerprint (the host key is then scanned through the jump host).
 //
 // Parameters:
 //   - host: Remote host IP address or hostname
 //   - user: SSH username for authentication
 //   - port: SSH port (default: 22)
 //   - sshKey: SSH private key for authentication
+//   - knownHosts: known_hosts file containing the host key (optional)
+//   - hostKeyFingerprint: Pinned host key fingerprint (optional)
+//   - insecureIgnoreHostKey: Skip host key verification, vulnerable to MITM (default: false)
+//   - jumpHost: Jump host (bastion) IP address or hostname (optional)
+//   - jumpUser: SSH username on the jump host (default: user)
+//   - jumpPort: SSH port of the jump host (default: 22)
+//   - jumpKey: SSH private key for the jump host (default: sshKey)
+//   - jumpHostKeyFingerprint: Pinned jump host key fingerprint (required with hostKeyFingerprint)
 //
 // Example:
 //
@@ -21,6 +45,16 @@ import (
 //	  --host 172.16.24.97 \
 //	  --user admincd24 \
 //	  --ssh-key env:SSH_PRIVATE_KEY \
+//	  --known-hosts ~/.ssh/known_hosts \
+//	  deploy --source . --project-name myapp
+//
+//	dagger call with-context \
+//	  --host 10.10.0.5 \
+//	  --user deploy \
+//	  --ssh-key env:SSH_PRIVATE_KEY \
+//	  --known-hosts ~/.ssh/known_hosts \
+//	  --jump-host bastion.example.com \
+//	  --jump-user jump \
 //	  deploy --source . --project-name myapp
 func (m *DockerCompose) WithContext(
 	host string,
@@ -29,22 +63,110 @@ func (m *DockerCompose) WithContext(
 	// +default=22
 	port int,
 	sshKey *dagger.Secret,
-) *DockerCompose {
+	// +optional
+	knownHosts *dagger.File,
+	// +optional
+	hostKeyFingerprint string,
+	// +optional
+	// +default=false
+	insecureIgnoreHostKey bool,
+	// +optional
+	jumpHost string,
+	// +optional
+	jumpUser string,
+	// +optional
+	// +default=22
+	jumpPort int,
+	// +optional
+	jumpKey *dagger.Secret,
+	// +optional
+	jumpHostKeyFingerprint string,
+) (*DockerCompose, error) {
 	if port == 0 {
 		port = 22
 	}
+	if err := validateHostKeyOptions(knownHosts, hostKeyFingerprint, insecureIgnoreHostKey); err != nil {
+		return nil, fmt.Errorf("%s: %w", host, err)
+	}
+
+	if jumpHost == "" {
+		if jumpUser != "" || jumpKey != nil || jumpHostKeyFingerprint != "" {
+			return nil, fmt.Errorf("%s: jumpUser, jumpKey and jumpHostKeyFingerprint require jumpHost", host)
+		}
+		jumpPort = 0
+	} else {
+		if jumpUser == "" {
+			jumpUser = user
+		}
+		if jumpPort == 0 {
+			jumpPort = 22
+		}
+		if jumpKey == nil {
+			jumpKey = sshKey
+		}
+		if err := validateJumpHostKeyOptions(hostKeyFingerprint, jumpHostKeyFingerprint); err != nil {
+			return nil, fmt.Errorf("%s: %w", host, err)
+		}
+	}
 
 	return &DockerCompose{
 		RegistryHost:     m.RegistryHost,
 		RegistryUsername: m.RegistryUsername,
 		RegistryPassword: m.RegistryPassword,
 		Variables:        copyVariables(m.Variables),
-		SSHHost:          host,
-		SSHUser:          user,
-		SSHPort:          port,
-		SSHKey:           sshKey,
-		EnvFile:          m.EnvFile,
+		Hosts: setHost(copyHosts(m.Hosts), &SSHHost{
+			Host:                  host,
+			User:                  user,
+			Port:                  port,
+			Key:                   sshKey,
+			KnownHosts:            knownHosts,
+			HostKeyFingerprint:    hostKeyFingerprint,
+			InsecureIgnoreHostKey: insecureIgnoreHostKey,
+
+			JumpHost:               jumpHost,
+			JumpUser:               jumpUser,
+			JumpPort:               jumpPort,
+			JumpKey:                jumpKey,
+			JumpHostKeyFingerprint: jumpHostKeyFingerprint,
+		}),
+		Parallelism:     m.Parallelism,
+		ContinueOnError: m.ContinueOnError,
+		EnvFile:         m.EnvFile,
+	}, nil
+}
+
+// validateHostKeyOptions requires exactly one host key verification option
+func validateHostKeyOptions(knownHosts *dagger.File, fingerprint string, insecure bool) error {
+	options := 0
+	for _, set := range []bool{knownHosts != nil, fingerprint != "", insecure} {
+		if set {
+			options++
+		}
 	}
+	switch {
+	case options == 0:
+		return fmt.Errorf("host key verification required: provide knownHosts or hostKeyFingerprint (or insecureIgnoreHostKey to disable verification)")
+	case options > 1:
+		return fmt.Errorf("knownHosts, hostKeyFingerprint and insecureIgnoreHostKey are mutually exclusive")
+	case fingerprint != "" && !strings.HasPrefix(fingerprint, "SHA256:"):
+		return fmt.Errorf("invalid host key fingerprint %q: expected SHA256:... as printed by ssh-keygen -lf", fingerprint)
+	}
+	return nil
+}
+
+// validateJumpHostKeyOptions checks the jump host key verification: a pinned
+// fingerprint for the host requires one for the jump host, other modes also
+// apply to the jump host
+func validateJumpHostKeyOptions(fingerprint, jumpFingerprint string) error {
+	switch {
+	case fingerprint != "" && jumpFingerprint == "":
+		return fmt.Errorf("jumpHostKeyFingerprint is required with hostKeyFingerprint")
+	case fingerprint == "" && jumpFingerprint != "":
+		return fmt.Errorf("jumpHostKeyFingerprint is only used with hostKeyFingerprint, otherwise the jump host key is verified like the host key")
+	case jumpFingerprint != "" && !strings.HasPrefix(jumpFingerprint, "SHA256:"):
+		return fmt.Errorf("invalid jump host key fingerprint %q: expected SHA256:... as printed by ssh-keygen -lf", jumpFingerprint)
+	}
+	return nil
 }
 
 // copyVariables creates a deep copy of the variables slice
@@ -62,3 +184,40 @@ func copyVariables(src []*Variable) []*Variable {
 	}
 	return dst
 }
+
+// copyHosts creates a deep copy of the hosts slice
+func copyHosts(src []*SSHHost) []*SSHHost {
+	if src == nil {
+		return []*SSHHost{}
+	}
+	dst := make([]*SSHHost, len(src))
+	for i, h := range src {
+		dst[i] = &SSHHost{
+			Host:                  h.Host,
+			User:                  h.User,
+			Port:                  h.Port,
+			Key:                   h.Key,
+			KnownHosts:            h.KnownHosts,
+			HostKeyFingerprint:    h.HostKeyFingerprint,
+			InsecureIgnoreHostKey: h.InsecureIgnoreHostKey,
+
+			JumpHost:               h.JumpHost,
+			JumpUser:               h.JumpUser,
+			JumpPort:               h.JumpPort,
+			JumpKey:                h.JumpKey,
+			JumpHostKeyFingerprint: h.JumpHostKeyFingerprint,
+		}
+	}
+	return dst
+}
+
+// setHost adds a host, replacing an existing entry with the same host and port
+func setHost(hosts []*SSHHost, host *SSHHost) []*SSHHost {
+	for i, h := range hosts {
+		if h.Host == host.Host && h.Port == host.Port {
+			hosts[i] = host
+			return hosts
+		}
+	}
+	return append(hosts, host)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidateJumpHostKeyOptions(t *testing.T) {
+	const fingerprint = "SHA256:Dlnh/skgsuA0FHw6gwpZ9yFVjpDUYcFALkS/sv7rm9E"
+
+	if err := validateJumpHostKeyOptions(fingerprint, fingerprint); err != nil {
+		t.Errorf("both hosts pinned: %v", err)
+	}
+	if err := validateJumpHostKeyOptions("", ""); err != nil {
+		t.Errorf("jump host verified like the host: %v", err)
+	}
+	// A pinned host behind an unverified jump host would be scanned through a
+	// possible MITM
+	if err := validateJumpHostKeyOptions(fingerprint, ""); err == nil {
+		t.Error("jump host fingerprint missing: no error")
+	}
+	if err := validateJumpHostKeyOptions("", fingerprint); err == nil {
+		t.Error("only jump host pinned: no error")
+	}
+}
@@ -24,10 +24,9 @@ func (m *DockerCompose) WithEnvFile(
 		RegistryUsername: m.RegistryUsername,
 		RegistryPassword: m.RegistryPassword,
 		Variables:        copyVariables(m.Variables),
-		SSHHost:          m.SSHHost,
-		SSHUser:          m.SSHUser,
-		SSHPort:          m.SSHPort,
-		SSHKey:           m.SSHKey,
+		Hosts:            copyHosts(m.Hosts),
+		Parallelism:      m.Parallelism,
+		ContinueOnError:  m.ContinueOnError,
 		EnvFile:          envFile,
 	}
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dagger/docker-compose/internal/dagger"
+)
+
+// WithHosts configures several remote Docker hosts sharing the same SSH credentials
+//
+// Equivalent to calling WithContext once per host. Each entry can override the
+// user and port (e.g., "deploy@10.0.0.5:2222").
+// Host keys are verified against the known_hosts file, which must contain every
+// host, unless verification is explicitly disabled with insecureIgnoreHostKey.
+// All hosts can be reached through the same jump host (bastion), whose key must
+// also be in the known_hosts file.
+//
+// Parameters:
+//   - hosts: Remote hosts ("host", "host:port" or "user@host:port")
+//   - user: Default SSH username
+//   - port: Default SSH port (default: 22)
+//   - sshKey: SSH private key for authentication
+//   - knownHosts: known_hosts file containing the host keys (optional)
+//   - insecureIgnoreHostKey: Skip host key verification, vulnerable to MITM (default: false)
+//   - jumpHost: Jump host ("host", "host:port" or "user@host:port", optional)
+//   - jumpKey: SSH private key for the jump host (default: sshKey)
+//
+// Example:
+//
+//	dagger call with-hosts \
+//	  --hosts 10.0.0.11,10.0.0.12,10.0.0.13 \
+//	  --user admin \
+//	  --ssh-key env:SSH_PRIVATE_KEY \
+//	  --known-hosts ~/.ssh/known_hosts \
+//	  --jump-host jump@bastion.example.com \
+//	  with-parallelism --parallelism 2 \
+//	  deploy --source . --project-name myapp
+func (m *DockerCompose) WithHosts(
+	hosts []string,
+	user string,
+	// +optional
+	// +default=22
+	port int,
+	sshKey *dagger.Secret,
+	// +optional
+	knownHosts *dagger.File,
+	// +optional
+	// +default=false
+	insecureIgnoreHostKey bool,
+	// +optional
+	jumpHost string,
+	// +optional
+	jumpKey *dagger.Secret,
+) (*DockerCompose, error) {
+	if port == 0 {
+		port = 22
+	}
+
+	var jumpUser string
+	var jumpPort int
+	if jumpHost != "" {
+		var err error
+		if jumpUser, jumpHost, jumpPort, err = parseHostEntry(jumpHost, user, 22); err != nil {
+			return nil, fmt.Errorf("jump host: %w", err)
+		}
+	}
+
+	d := m
+	for _, entry := range hosts {
+		hostUser, host, hostPort, err := parseHostEntry(entry, user, port)
+		if err != nil {
+			return nil, err
+		}
+		if d, err = d.WithContext(host, hostUser, hostPort, sshKey, knownHosts, "", insecureIgnoreHostKey,
+			jumpHost, jumpUser, jumpPort, jumpKey, ""); err != nil {
+			return nil, err
+		}
+	}
+	if d == m {
+		return nil, fmt.Errorf("no host provided")
+	}
+	return d, nil
+}
+
+// parseHostEntry parses "host", "host:port" or "user@host:port"
+func parseHostEntry(entry, defaultUser string, defaultPort int) (string, string, int, error) {
+	entry = strings.TrimSpace(entry)
+	user := defaultUser
+	if u, rest, found := strings.Cut(entry, "@"); found {
+		user, entry = u, rest
+	}
+
+	host := entry
+	port := defaultPort
+	if h, p, found := strings.Cut(entry, ":"); found {
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 || n > 65535 {
+			return "", "", 0, fmt.Errorf("invalid port in host %q", entry)
+		}
+		host, port = h, n
+	}
+
+	if host == "" || user == "" {
+		return "", "", 0, fmt.Errorf("invalid host %q: host and user are required", entry)
+	}
+	return user, host, port, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseHostEntry(t *testing.T) {
+	tests := []struct {
+		entry    string
+		wantUser string
+		wantHost string
+		wantPort int
+	}{
+		{"web1.example.com", "deploy", "web1.example.com", 22},
+		{"web1.example.com:2222", "deploy", "web1.example.com", 2222},
+		{"admin@10.0.0.5:2222", "admin", "10.0.0.5", 2222},
+	}
+	for _, tt := range tests {
+		user, host, port, err := parseHostEntry(tt.entry, "deploy", 22)
+		if err != nil || user != tt.wantUser || host != tt.wantHost || port != tt.wantPort {
+			t.Errorf("parseHostEntry(%q) = %q, %q, %d, %v; want %q, %q, %d",
+				tt.entry, user, host, port, err, tt.wantUser, tt.wantHost, tt.wantPort)
+		}
+	}
+
+	for _, entry := range []string{"web1.example.com:ssh", "web1.example.com:70000", "admin@", ""} {
+		if _, _, _, err := parseHostEntry(entry, "deploy", 22); err == nil {
+			t.Errorf("parseHostEntry(%q) succeeded", entry)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+)
+
+// WithParallelism configures how a fleet of hosts is handled
+//
+// Deploy, Status, Down and Logs run against every host configured with
+// WithContext or WithHosts. By default hosts are handled one at a time and
+// the remaining hosts are skipped after the first failure (fail-fast).
+//
+// Parameters:
+//   - parallelism: Number of hosts handled at the same time (default: 1)
+//   - continueOnError: Keep going with the remaining hosts after a failure (default: false)
+//
+// Example:
+//
+//	dagger call with-hosts --hosts 10.0.0.11,10.0.0.12 --user admin --ssh-key env:SSH_KEY \
+//	  with-parallelism --parallelism 2 --continue-on-error \
+//	  status --source .
+func (m *DockerCompose) WithParallelism(
+	// +optional
+	// +default=1
+	parallelism int,
+	// +optional
+	// +default=false
+	continueOnError bool,
+) (*DockerCompose, error) {
+	if parallelism == 0 {
+		parallelism = 1
+	}
+	if parallelism < 0 {
+		return nil, fmt.Errorf("parallelism must be positive")
+	}
+
+	return &DockerCompose{
+		RegistryHost:     m.RegistryHost,
+		RegistryUsername: m.RegistryUsername,
+		RegistryPassword: m.RegistryPassword,
+		Variables:        copyVariables(m.Variables),
+		Hosts:            copyHosts(m.Hosts),
+		Parallelism:      parallelism,
+		ContinueOnError:  continueOnError,
+		EnvFile:          m.EnvFile,
+	}, nil
+}
@@ -29,10 +29,9 @@ func (m *DockerCompose) WithRegistry(
 		RegistryUsername: username,
 		RegistryPassword: password,
 		Variables:        copyVariables(m.Variables),
-		SSHHost:          m.SSHHost,
-		SSHUser:          m.SSHUser,
-		SSHPort:          m.SSHPort,
-		SSHKey:           m.SSHKey,
+		Hosts:            copyHosts(m.Hosts),
+		Parallelism:      m.Parallelism,
+		ContinueOnError:  m.ContinueOnError,
 		EnvFile:          m.EnvFile,
 	}
 }
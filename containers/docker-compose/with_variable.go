@@ -28,10 +28,9 @@ func (m *DockerCompose) WithVariable(
 		RegistryUsername: m.RegistryUsername,
 		RegistryPassword: m.RegistryPassword,
 		Variables:        newVars,
-		SSHHost:          m.SSHHost,
-		SSHUser:          m.SSHUser,
-		SSHPort:          m.SSHPort,
-		SSHKey:           m.SSHKey,
+		Hosts:            copyHosts(m.Hosts),
+		Parallelism:      m.Parallelism,
+		ContinueOnError:  m.ContinueOnError,
 		EnvFile:          m.EnvFile,
 	}
 }
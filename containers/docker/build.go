@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"dagger/docker/internal/dagger"
 )
@@ -9,7 +12,10 @@ import (
 // Build builds a Docker image from a Dockerfile using Dagger's native build
 //
 // Returns a container that can be exported or pushed to a registry.
+// When several platforms are configured via WithPlatform(), the variant for the
+// first platform is returned; use BuildPlatforms() to get every variant.
 func (m *Docker) Build(
+	ctx context.Context,
 	// Directory containing Dockerfile and build context
 	// +ignore=[".git", "**/.gitignore"]
 	source *dagger.Directory,
@@ -20,6 +26,30 @@ func (m *Docker) Build(
 	// +default="Dockerfile"
 	dockerfile string,
 ) (*dagger.Container, error) {
+	variants, err := m.BuildPlatforms(ctx, source, imageName, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+
+	return variants[0], nil
+}
+
+// BuildPlatforms builds one image variant per configured platform
+//
+// Returns one container per platform, in the order they were added with
+// WithPlatform(). Pass them to Push() to publish a multi-platform image index.
+func (m *Docker) BuildPlatforms(
+	ctx context.Context,
+	// Directory containing Dockerfile and build context
+	// +ignore=[".git", "**/.gitignore"]
+	source *dagger.Directory,
+	// Image name for reference (e.g., "myapp")
+	imageName string,
+	// Path to Dockerfile relative to source
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+) ([]*dagger.Container, error) {
 	if err := validateImageName(imageName); err != nil {
 		return nil, fmt.Errorf("invalid image name: %w", err)
 	}
@@ -28,10 +58,120 @@ func (m *Docker) Build(
 		dockerfile = "Dockerfile"
 	}
 
+	secrets, byName, err := m.dockerBuildSecrets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Register credentials so private base images can be pulled
+	if err := m.registerRegistryAuth(ctx); err != nil {
+		return nil, err
+	}
+
+	platforms := m.getPlatforms()
+	variants := make([]*dagger.Container, 0, len(platforms))
+	for _, platform := range platforms {
+		variants = append(variants, m.buildVariant(source, imageName, dockerfile, platform, secrets, !byName))
+	}
+
+	return variants, nil
+}
+
+// buildVariant builds the image for a single platform
+func (m *Docker) buildVariant(
+	source *dagger.Directory,
+	imageName string,
+	dockerfile string,
+	platform dagger.Platform,
+	secrets []*dagger.Secret,
+	buildkit bool,
+) *dagger.Container {
+	var container *dagger.Container
+	if m.CacheRef != "" || buildkit {
+		// Registry cache and secrets mounted by id require BuildKit directly
+		container = m.buildWithBuildKit(source, imageName, dockerfile, platform)
+	} else {
+		container = m.dockerBuild(source, dockerfile, platform, secrets)
+	}
+
+	// Add OCI standard labels, then user labels so they can override them
+	for _, label := range m.ociLabels(imageName) {
+		container = container.WithLabel(label.Key, label.Value)
+	}
+	for _, label := range m.Labels {
+		container = container.WithLabel(label.Key, label.Value)
+	}
+
+	return container
+}
+
+// ociLabels returns the OCI standard labels for the image
+// (see https://github.com/opencontainers/image-spec/blob/main/annotations.md)
+func (m *Docker) ociLabels(imageName string) []DockerKeyValue {
+	version, tag := m.versionTag()
+
+	labels := []DockerKeyValue{
+		{Key: "org.opencontainers.image.title", Value: imageName},
+		{Key: "org.opencontainers.image.version", Value: version},
+		{Key: "org.opencontainers.image.ref.name", Value: buildFullReference(m.primaryRegistryHost(), imageName, tag)},
+	}
+
+	// A build time would give every build a new digest
+	if created := m.createdTime(); created != "" {
+		labels = append(labels, DockerKeyValue{Key: "org.opencontainers.image.created", Value: created})
+	}
+
+	if m.GitRevision != "" {
+		labels = append(labels, DockerKeyValue{Key: "org.opencontainers.image.revision", Value: m.GitRevision})
+	}
+	if m.GitSource != "" {
+		labels = append(labels, DockerKeyValue{Key: "org.opencontainers.image.source", Value: m.GitSource})
+	}
+
+	return labels
+}
+
+// createdTime returns the image creation time: the SOURCE_DATE_EPOCH build
+// argument if set, otherwise the commit time of the git revision. Empty when
+// neither is known, so rebuilding the same source gives the same digest.
+func (m *Docker) createdTime() string {
+	for _, arg := range m.BuildArgs {
+		if arg.Key == "SOURCE_DATE_EPOCH" {
+			if seconds, err := strconv.ParseInt(arg.Value, 10, 64); err == nil {
+				return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	if m.GitRevision != "" {
+		return m.GitCommitTime
+	}
+	return ""
+}
+
+// versionTag returns the image version and the tag it is published under: the
+// first configured semantic version (e.g., v1.2.3 rather than the commit SHA
+// added first by WithGitTags), or the first tag when no tag is a version
+func (m *Docker) versionTag() (string, string) {
+	for _, tag := range m.Tags {
+		if isSemanticVersion(tag) {
+			return tag, m.expandTag(tag)[0]
+		}
+	}
+	tags := m.getDefaultTags()
+	return tags[0], tags[0]
+}
+
+// dockerBuild builds the image for a single platform using Directory.DockerBuild()
+func (m *Docker) dockerBuild(
+	source *dagger.Directory,
+	dockerfile string,
+	platform dagger.Platform,
+	secrets []*dagger.Secret,
+) *dagger.Container {
 	// Build options
 	buildOpts := dagger.DirectoryDockerBuildOpts{
 		Dockerfile: dockerfile,
-		Platform:   m.Platform,
+		Platform:   platform,
 	}
 
 	// Set target if configured
@@ -51,15 +191,43 @@ func (m *Docker) Build(
 		buildOpts.BuildArgs = buildArgs
 	}
 
+	// Add build secrets (mounted only for RUN --mount=type=secret)
+	if len(secrets) > 0 {
+		buildOpts.Secrets = secrets
+	}
+
 	// Build container using Directory.DockerBuild()
-	container := source.DockerBuild(buildOpts)
+	return source.DockerBuild(buildOpts)
+}
 
-	// Add image reference as label
-	tags := m.getDefaultTags()
-	for _, tag := range tags {
-		fullRef := buildFullReference(m.RegistryHost, imageName, tag)
-		container = container.WithLabel("org.opencontainers.image.ref.name", fullRef)
+// dockerBuildSecrets returns the build secrets for DockerBuild(), which exposes
+// each secret under its name rather than the id expected by
+// RUN --mount=type=secret,id=<id>. Returns false if a secret is not named after
+// its id: such builds run with BuildKit, which mounts the original secrets by id,
+// so secret values are never read by the module.
+func (m *Docker) dockerBuildSecrets(ctx context.Context) ([]*dagger.Secret, bool, error) {
+	secrets := make([]*dagger.Secret, 0, len(m.BuildSecrets))
+	for _, s := range m.BuildSecrets {
+		name, err := s.Secret.Name(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read build secret %s: %w", s.ID, err)
+		}
+		if name != s.ID {
+			return nil, false, nil
+		}
+		secrets = append(secrets, s.Secret)
 	}
+	return secrets, true, nil
+}
 
-	return container, nil
+// registerRegistryAuth makes the configured registry credentials available to
+// the engine before DockerBuild() resolves FROM images
+func (m *Docker) registerRegistryAuth(ctx context.Context) error {
+	if len(m.registryCredentials()) == 0 {
+		return nil
+	}
+	if _, err := m.withRegistryAuth(dag.Container()).Sync(ctx); err != nil {
+		return fmt.Errorf("failed to configure registry authentication: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"dagger/docker/internal/dagger"
+	"golang.org/x/sync/errgroup"
+)
+
+// BuildResult is the result of building and pushing one image with BuildAll()
+type BuildResult struct {
+	// Image name from the build manifest
+	Image string
+	// Last published reference (e.g., "myacr.azurecr.io/myapp:latest")
+	Reference string
+	// Manifest or index digest (e.g., "sha256:...")
+	Digest string
+}
+
+// buildManifest is the build manifest accepted by BuildAll()
+type buildManifest struct {
+	Images []buildManifestImage `json:"images"`
+}
+
+// buildManifestImage describes one image of the build manifest
+type buildManifestImage struct {
+	Name       string            `json:"name"`
+	Context    string            `json:"context"`
+	Dockerfile string            `json:"dockerfile"`
+	Target     string            `json:"target"`
+	Args       map[string]string `json:"args"`
+	Platforms  []dagger.Platform `json:"platforms"`
+	Tags       []string          `json:"tags"`
+	DependsOn  []string          `json:"dependsOn"`
+}
+
+// invalidArgChars matches characters not allowed in build argument names
+var invalidArgChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// BuildAll builds and pushes every image listed in a build manifest
+//
+// The manifest (YAML or JSON) lists the images to build:
+//
+//	images:
+//	  - name: base
+//	    context: images/base
+//	  - name: api
+//	    context: services/api
+//	    dockerfile: Dockerfile.prod
+//	    target: runtime
+//	    args:
+//	      GO_VERSION: "1.23"
+//	    platforms: [linux/amd64, linux/arm64]
+//	    tags: [v1.2.3]
+//	    dependsOn: [base]
+//
+// Images without dependencies are built concurrently; an image is built only
+// once all images it depends on have been pushed. The digest reference of each
+// dependency is passed as a build argument named after it (e.g., BASE_IMAGE
+// for "base"), so Dockerfiles can pin it with ARG BASE_IMAGE / FROM ${BASE_IMAGE}.
+// Unset fields inherit the module configuration (WithArg(), WithPlatform(),
+// WithTag(), WithTarget()).
+// Requires registry authentication configured via WithRegistry().
+// Returns one result per image, in manifest order.
+func (m *Docker) BuildAll(
+	ctx context.Context,
+	// Directory containing the build manifest and the build contexts
+	// +ignore=[".git", "**/.gitignore"]
+	source *dagger.Directory,
+	// Path to the build manifest relative to source
+	// +optional
+	// +default="docker-build.yaml"
+	manifest string,
+	// Maximum number of images built at the same time
+	// +optional
+	// +default=4
+	concurrency int,
+) ([]*BuildResult, error) {
+	if len(m.pushRegistries()) == 0 {
+		return nil, fmt.Errorf("registry not configured: use WithRegistry() before BuildAll()")
+	}
+	if manifest == "" {
+		manifest = "docker-build.yaml"
+	}
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	manifestJSON, err := yamlToJSON(ctx, source.File(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build manifest %s: %w", manifest, err)
+	}
+
+	var spec buildManifest
+	if err := json.Unmarshal([]byte(manifestJSON), &spec); err != nil {
+		return nil, fmt.Errorf("invalid build manifest %s: %w", manifest, err)
+	}
+
+	levels, err := buildLevels(spec.Images)
+	if err != nil {
+		return nil, fmt.Errorf("invalid build manifest %s: %w", manifest, err)
+	}
+
+	results := map[string]*BuildResult{}
+	var mu sync.Mutex
+
+	for _, level := range levels {
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(concurrency)
+
+		for _, image := range level {
+			// Dependencies were pushed by a previous level
+			depArgs := map[string]string{}
+			for _, dep := range image.DependsOn {
+				r := results[dep]
+				depArgs[dependencyArg(dep)] = buildRepository(m.primaryRegistryHost(), dep) + "@" + r.Digest
+			}
+
+			g.Go(func() error {
+				result, err := m.buildManifestImage(gctx, source, image, depArgs)
+				if err != nil {
+					return fmt.Errorf("%s: %w", image.Name, err)
+				}
+				mu.Lock()
+				results[image.Name] = result
+				mu.Unlock()
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	ordered := make([]*BuildResult, 0, len(spec.Images))
+	for _, image := range spec.Images {
+		ordered = append(ordered, results[image.Name])
+	}
+	return ordered, nil
+}
+
+// buildManifestImage builds and pushes one image of the build manifest
+func (m *Docker) buildManifestImage(
+	ctx context.Context,
+	source *dagger.Directory,
+	image buildManifestImage,
+	depArgs map[string]string,
+) (*BuildResult, error) {
+	d := m.clone()
+
+	if image.Target != "" {
+		d.Target = image.Target
+	}
+	if len(image.Platforms) > 0 {
+		d.Platforms = []dagger.Platform{}
+		for _, platform := range image.Platforms {
+			if !slices.Contains(d.Platforms, platform) {
+				d.Platforms = append(d.Platforms, platform)
+			}
+		}
+	}
+	if len(image.Tags) > 0 {
+		d.Tags = image.Tags
+	}
+
+	// Manifest arguments override module arguments, sorted for reproducible builds
+	args := map[string]string{}
+	for k, v := range image.Args {
+		args[k] = v
+	}
+	for k, v := range depArgs {
+		args[k] = v
+	}
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d.BuildArgs = slices.DeleteFunc(d.BuildArgs, func(a DockerBuildArg) bool { return a.Key == k })
+		d.BuildArgs = append(d.BuildArgs, DockerBuildArg{Key: k, Value: args[k]})
+	}
+
+	// Each image needs its own registry cache
+	if d.CacheRef != "" {
+		d.CacheRef += "-" + sanitizeTag(image.Name)
+	}
+
+	buildContext := source
+	if image.Context != "" && image.Context != "." {
+		buildContext = source.Directory(image.Context)
+	}
+
+	published, err := d.BuildAndPush(ctx, buildContext, image.Name, image.Dockerfile)
+	if err != nil {
+		return nil, err
+	}
+
+	reference, digest, _ := strings.Cut(published, "@")
+	return &BuildResult{
+		Image:     image.Name,
+		Reference: reference,
+		Digest:    digest,
+	}, nil
+}
+
+// buildLevels groups images by dependency depth: every image only depends on
+// images of previous levels
+func buildLevels(images []buildManifestImage) ([][]buildManifestImage, error) {
+	byName := map[string]buildManifestImage{}
+	for _, image := range images {
+		if image.Name == "" {
+			return nil, fmt.Errorf("image name is required")
+		}
+		if err := validateImageName(image.Name); err != nil {
+			return nil, err
+		}
+		if _, ok := byName[image.Name]; ok {
+			return nil, fmt.Errorf("duplicate image: %s", image.Name)
+		}
+		byName[image.Name] = image
+	}
+	for _, image := range images {
+		for _, dep := range image.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("%s depends on unknown image %s", image.Name, dep)
+			}
+		}
+	}
+
+	var levels [][]buildManifestImage
+	done := map[string]bool{}
+	for len(done) < len(images) {
+		var level []buildManifestImage
+		for _, image := range images {
+			if done[image.Name] {
+				continue
+			}
+			ready := true
+			for _, dep := range image.DependsOn {
+				ready = ready && done[dep]
+			}
+			if ready {
+				level = append(level, image)
+			}
+		}
+
+		if len(level) == 0 {
+			var pending []string
+			for _, image := range images {
+				if !done[image.Name] {
+					pending = append(pending, image.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(pending, ", "))
+		}
+
+		for _, image := range level {
+			done[image.Name] = true
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, nil
+}
+
+// dependencyArg returns the build argument carrying a dependency reference
+// (e.g., "base" -> BASE_IMAGE, "myorg/go-builder" -> MYORG_GO_BUILDER_IMAGE)
+func dependencyArg(name string) string {
+	return strings.Trim(invalidArgChars.ReplaceAllString(strings.ToUpper(name), "_"), "_") + "_IMAGE"
+}
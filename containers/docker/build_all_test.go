@@ -0,0 +1,48 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// levelNames returns the image names of each build level
+func levelNames(levels [][]buildManifestImage) [][]string {
+	var names [][]string
+	for _, level := range levels {
+		var imageNames []string
+		for _, image := range level {
+			imageNames = append(imageNames, image.Name)
+		}
+		names = append(names, imageNames)
+	}
+	return names
+}
+
+func TestBuildLevels(t *testing.T) {
+	levels, err := buildLevels([]buildManifestImage{
+		{Name: "api", DependsOn: []string{"builder"}},
+		{Name: "builder", DependsOn: []string{"base"}},
+		{Name: "base"},
+		{Name: "worker", DependsOn: []string{"base"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"base"}, {"builder", "worker"}, {"api"}}
+	if got := levelNames(levels); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("buildLevels() = %v, want %v", got, want)
+	}
+}
+
+func TestBuildLevelsInvalidManifest(t *testing.T) {
+	tests := map[string][]buildManifestImage{
+		"cycle":              {{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+		"unknown dependency": {{Name: "app", DependsOn: []string{"base"}}},
+		"duplicate image":    {{Name: "app"}, {Name: "app"}},
+	}
+	for name, images := range tests {
+		if _, err := buildLevels(images); err == nil {
+			t.Errorf("%s: buildLevels() succeeded", name)
+		}
+	}
+}
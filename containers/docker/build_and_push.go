@@ -11,6 +11,11 @@ import (
 //
 // Convenience function combining Build() and Push().
 // Requires registry authentication configured via WithRegistry().
+// All configured platforms are published as a single image index to every
+// push registry configured via WithRegistry().
+// When a scan gate is configured (see WithScanGate()), the push is refused if
+// the scan finds blocking vulnerabilities.
+// The registry build cache (see WithCache()) is refreshed after a successful push.
 // Returns the image digest.
 func (m *Docker) BuildAndPush(
 	ctx context.Context,
@@ -25,21 +30,35 @@ func (m *Docker) BuildAndPush(
 	dockerfile string,
 ) (string, error) {
 	// Validate registry early (fail fast)
-	if m.RegistryHost == "" {
+	if len(m.pushRegistries()) == 0 {
 		return "", fmt.Errorf("registry not configured: use WithRegistry() before BuildAndPush()")
 	}
 
-	// Build
-	container, err := m.Build(source, imageName, dockerfile)
+	// Build one variant per platform
+	variants, err := m.BuildPlatforms(ctx, source, imageName, dockerfile)
 	if err != nil {
 		return "", fmt.Errorf("build failed: %w", err)
 	}
 
+	// Refuse to push images with blocking vulnerabilities
+	if m.ScanDB != nil {
+		if err := m.scanGate(ctx, variants); err != nil {
+			return "", err
+		}
+	}
+
 	// Push
-	digest, err := m.Push(ctx, container, imageName)
+	digest, err := m.Push(ctx, variants[0], imageName, variants[1:])
 	if err != nil {
 		return "", fmt.Errorf("push failed: %w", err)
 	}
 
+	// Refresh registry cache once the image is published
+	if m.CacheRef != "" {
+		if err := m.exportCache(ctx, source, imageName, dockerfile); err != nil {
+			return "", err
+		}
+	}
+
 	return digest, nil
 }
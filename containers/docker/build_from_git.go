@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
+)
+
+// gitImage provides git and the OpenSSH client
+const gitImage = "alpine/git:2.45.2"
+
+// gitCloneScript fetches a single ref into /src and records the commit
+const gitCloneScript = `set -e
+git init -q /src
+cd /src
+git remote add origin "$GIT_URL"
+git fetch -q --depth 1 origin "$GIT_REF"
+git checkout -q FETCH_HEAD
+git rev-parse HEAD > /tmp/revision
+git show -s --format=%cI HEAD > /tmp/commit_time
+rm -rf .git
+`
+
+// commitPattern matches full commit SHAs, which never need to be refetched
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// BuildFromGit builds a Docker image from a remote Git repository
+//
+// The repository is fetched at the given ref without a local checkout, so
+// scheduled jobs can rebuild upstream projects at a pinned commit.
+// HTTPS repositories can be authenticated with a token, SSH repositories with
+// a private key. The SSH host key is verified against a known_hosts file or a
+// pinned fingerprint; verification can only be disabled explicitly with
+// insecureIgnoreHostKey. The commit, its time and the repository URL are
+// recorded in the OCI revision, created and source labels.
+func (m *Docker) BuildFromGit(
+	ctx context.Context,
+	// Repository URL (e.g., "https://github.com/org/app.git" or "git@github.com:org/app.git")
+	url string,
+	// Image name for reference (e.g., "myapp")
+	imageName string,
+	// Branch, tag or commit SHA to build
+	// +optional
+	// +default="HEAD"
+	ref string,
+	// Build context directory relative to the repository root
+	// +optional
+	subdir string,
+	// Path to Dockerfile relative to the build context
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Token for HTTPS repositories (e.g., a GitHub or GitLab access token)
+	// +optional
+	token *dagger.Secret,
+	// Private key for SSH repositories
+	// +optional
+	sshKey *dagger.Secret,
+	// known_hosts file containing the SSH host key
+	// +optional
+	knownHosts *dagger.File,
+	// Pinned SSH host key fingerprint (e.g., "SHA256:...", as printed by ssh-keygen -lf)
+	// +optional
+	hostKeyFingerprint string,
+	// Skip SSH host key verification, vulnerable to MITM
+	// +optional
+	// +default=false
+	insecureIgnoreHostKey bool,
+) (*dagger.Container, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if token != nil && sshKey != nil {
+		return nil, fmt.Errorf("token and sshKey are mutually exclusive")
+	}
+	if sshKey == nil && (knownHosts != nil || hostKeyFingerprint != "" || insecureIgnoreHostKey) {
+		return nil, fmt.Errorf("knownHosts, hostKeyFingerprint and insecureIgnoreHostKey require sshKey")
+	}
+
+	var source *dagger.Directory
+	var revision, committed string
+	if sshKey != nil {
+		if err := validateHostKeyOptions(knownHosts, hostKeyFingerprint, insecureIgnoreHostKey); err != nil {
+			return nil, err
+		}
+
+		// Only keep the host keys matching the pinned fingerprint
+		if hostKeyFingerprint != "" {
+			host, port, err := sshEndpoint(url)
+			if err != nil {
+				return nil, err
+			}
+			hostKeys := dag.Container().
+				From(gitImage).
+				WithEnvVariable("SSH_HOST", host).
+				WithEnvVariable("SSH_PORT", strconv.Itoa(port)).
+				WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
+				WithExec([]string{"sh", "-c", `ssh-keyscan -p "$SSH_PORT" "$SSH_HOST" > /tmp/host_keys 2>/dev/null || true`}).
+				File("/tmp/host_keys")
+			if knownHosts, err = pinnedKnownHosts(ctx, hostKeys, hostKeyFingerprint); err != nil {
+				return nil, fmt.Errorf("failed to verify the host key of %s: %w", host, err)
+			}
+		}
+
+		// dag.Git() only supports SSH agent sockets, so clone with a key in a git container
+		sshCommand := "ssh -i /run/secrets/ssh_key -o IdentitiesOnly=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null"
+		clone := dag.Container().
+			From(gitImage).
+			WithMountedSecret("/run/secrets/ssh_key", sshKey, dagger.ContainerWithMountedSecretOpts{Mode: 0o400}).
+			WithEnvVariable("GIT_URL", url).
+			WithEnvVariable("GIT_REF", ref)
+		if knownHosts != nil {
+			clone = clone.WithMountedFile("/run/secrets/known_hosts", knownHosts)
+			sshCommand = "ssh -i /run/secrets/ssh_key -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes -o UserKnownHostsFile=/run/secrets/known_hosts"
+		}
+		if !commitPattern.MatchString(ref) {
+			// Branches and tags move, never reuse a cached fetch
+			clone = clone.WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String())
+		}
+		clone = clone.
+			WithEnvVariable("GIT_SSH_COMMAND", sshCommand).
+			WithExec([]string{"sh", "-c", gitCloneScript})
+
+		output, err := clone.File("/tmp/revision").Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s at %s: %w", url, ref, err)
+		}
+		revision = strings.TrimSpace(output)
+		if output, err = clone.File("/tmp/commit_time").Contents(ctx); err != nil {
+			return nil, fmt.Errorf("failed to read the commit time of %s: %w", ref, err)
+		}
+		if committed, err = commitTime(output); err != nil {
+			return nil, err
+		}
+		source = clone.Directory("/src")
+	} else {
+		// The .git directory is only kept to read the commit time
+		opts := dagger.GitOpts{KeepGitDir: true}
+		if token != nil {
+			opts.HTTPAuthToken = token
+		}
+		gitRef := dag.Git(url, opts).Ref(ref)
+
+		var err error
+		if revision, err = gitRef.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s at %s: %w", url, ref, err)
+		}
+		tree := gitRef.Tree()
+		output, err := dag.Container().
+			From(gitImage).
+			WithMountedDirectory("/src", tree).
+			WithWorkdir("/src").
+			WithExec([]string{"sh", "-c", "git config --global --add safe.directory /src && git show -s --format=%cI HEAD"}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the commit time of %s: %w", ref, err)
+		}
+		if committed, err = commitTime(output); err != nil {
+			return nil, err
+		}
+		source = tree.WithoutDirectory(".git")
+	}
+
+	if subdir != "" {
+		source = source.Directory(subdir)
+	}
+
+	d := m.clone()
+	d.GitRevision = revision
+	d.GitCommitTime = committed
+	d.GitSource = stripURLCredentials(url)
+	return d.Build(ctx, source, imageName, dockerfile)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"dagger/docker/internal/dagger"
+)
+
+// BuildFromTarball builds a Docker image from a source tarball
+//
+// The tarball may be compressed (gzip, bzip2, xz, zstd), e.g., a release
+// archive of an upstream project. Use stripComponents to drop the top-level
+// directory most archives contain.
+func (m *Docker) BuildFromTarball(
+	ctx context.Context,
+	// Tarball containing the Dockerfile and build context
+	tarball *dagger.File,
+	// Image name for reference (e.g., "myapp")
+	imageName string,
+	// Number of leading path components to strip when extracting
+	// +optional
+	// +default=0
+	stripComponents int,
+	// Build context directory relative to the extracted tarball
+	// +optional
+	subdir string,
+	// Path to Dockerfile relative to the build context
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+) (*dagger.Container, error) {
+	if stripComponents < 0 {
+		return nil, fmt.Errorf("stripComponents must not be negative")
+	}
+
+	source := jqContainer().
+		WithExec([]string{"apk", "add", "--no-cache", "xz", "zstd"}).
+		WithMountedFile("/in.tar", tarball).
+		WithExec([]string{"mkdir", "-p", "/src"}).
+		WithExec([]string{
+			"tar", "-xf", "/in.tar",
+			"-C", "/src",
+			"--strip-components", strconv.Itoa(stripComponents),
+			"--no-same-owner",
+		}).
+		Directory("/src")
+
+	if subdir != "" {
+		source = source.Directory(subdir)
+	}
+
+	return m.Build(ctx, source, imageName, dockerfile)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestVersionTag(t *testing.T) {
+	tests := []struct {
+		name        string
+		tags        []string
+		metadata    string
+		wantVersion string
+		wantTag     string
+	}{
+		{"no tags", nil, "strip", "latest", "latest"},
+		{"commit SHA before version", []string{"a1b2c3d", "v1.2.3"}, "strip", "v1.2.3", "v1.2.3"},
+		{"branch and SHA only", []string{"a1b2c3d", "main"}, "strip", "a1b2c3d", "a1b2c3d"},
+		{"prerelease", []string{"v1.0.0-rc.1"}, "strip", "v1.0.0-rc.1", "v1.0.0-rc.1"},
+		{"build metadata stripped", []string{"v1.0.0+abc123"}, "strip", "v1.0.0+abc123", "v1.0.0"},
+		{"build metadata suffix", []string{"v1.0.0+abc123"}, "suffix", "v1.0.0+abc123", "v1.0.0_abc123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.Tags = tt.tags
+			m.TagBuildMetadata = tt.metadata
+			version, tag := m.versionTag()
+			if version != tt.wantVersion || tag != tt.wantTag {
+				t.Errorf("versionTag() = %q, %q; want %q, %q", version, tag, tt.wantVersion, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestCreatedTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		buildArgs  []DockerBuildArg
+		revision   string
+		commitTime string
+		want       string
+	}{
+		{"unknown", nil, "", "", ""},
+		{"git commit time", nil, "a1b2c3d4", "2026-03-01T10:00:00Z", "2026-03-01T10:00:00Z"},
+		{"SOURCE_DATE_EPOCH wins", []DockerBuildArg{{Key: "SOURCE_DATE_EPOCH", Value: "1700000000"}}, "a1b2c3d4", "2026-03-01T10:00:00Z", "2023-11-14T22:13:20Z"},
+		{"invalid SOURCE_DATE_EPOCH", []DockerBuildArg{{Key: "SOURCE_DATE_EPOCH", Value: "yesterday"}}, "", "", ""},
+	}
+	for _, tt := range tests {
+		m := New()
+		m.BuildArgs = tt.buildArgs
+		m.GitRevision = tt.revision
+		m.GitCommitTime = tt.commitTime
+		if got := m.createdTime(); got != tt.want {
+			t.Errorf("%s: createdTime() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
+)
+
+// buildkitImage is the BuildKit image used for builds that need features not
+// exposed by Directory.DockerBuild() (e.g., registry cache import/export)
+const buildkitImage = "moby/buildkit:v0.18.2"
+
+// buildkitContainer creates a daemonless BuildKit container with the build
+// context mounted at /src and a persistent BuildKit state directory.
+// The state is kept per image and platform, so builds of different images run
+// concurrently. BuildKit runs with InsecureRootCapabilities (privileged).
+func (m *Docker) buildkitContainer(source *dagger.Directory, imageName string, platform dagger.Platform) *dagger.Container {
+	state := "docker-buildkit-state-" + sanitizeTag(imageName) + "-" + strings.ReplaceAll(string(platform), "/", "-")
+	container := dag.Container().
+		From(buildkitImage).
+		WithMountedDirectory("/src", source).
+		WithMountedCache("/var/lib/buildkit", dag.CacheVolume(state), dagger.ContainerWithMountedCacheOpts{
+			Sharing: dagger.CacheSharingModeShared,
+		}).
+		WithEnvVariable("BUILDKITD_FLAGS", "--oci-worker-no-process-sandbox")
+
+	// Build secrets are mounted outside the context and passed with --secret
+	for _, s := range m.BuildSecrets {
+		container = container.WithMountedSecret("/run/build-secrets/"+s.ID, s.Secret)
+	}
+
+	return m.withRegistryAuthWrapper(container)
+}
+
+// buildctlArgs returns the buildctl arguments shared by cache import and export runs
+func (m *Docker) buildctlArgs(dockerfile string, platform dagger.Platform) []string {
+	args := []string{
+		"with-registry-auth", "buildctl-daemonless.sh", "build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=/src",
+		"--local", "dockerfile=" + path.Join("/src", path.Dir(dockerfile)),
+		"--opt", "filename=" + path.Base(dockerfile),
+		"--opt", "platform=" + string(platform),
+	}
+
+	if m.Target != "" {
+		args = append(args, "--opt", "target="+m.Target)
+	}
+
+	for _, arg := range m.BuildArgs {
+		args = append(args, "--opt", fmt.Sprintf("build-arg:%s=%s", arg.Key, arg.Value))
+	}
+
+	for _, s := range m.BuildSecrets {
+		args = append(args, "--secret", fmt.Sprintf("id=%s,src=/run/build-secrets/%s", s.ID, s.ID))
+	}
+
+	return args
+}
+
+// cacheRefFor returns the cache reference for a platform.
+// A per-platform tag suffix is used for multi-platform builds so that
+// variants do not overwrite each other's cache.
+func (m *Docker) cacheRefFor(platform dagger.Platform) string {
+	if len(m.getPlatforms()) <= 1 {
+		return m.CacheRef
+	}
+	suffix := strings.ReplaceAll(string(platform), "/", "-")
+	return m.CacheRef + "-" + suffix
+}
+
+// buildWithBuildKit builds the image for a single platform with BuildKit,
+// importing layers from the registry cache when configured
+func (m *Docker) buildWithBuildKit(
+	source *dagger.Directory,
+	imageName string,
+	dockerfile string,
+	platform dagger.Platform,
+) *dagger.Container {
+	args := m.buildctlArgs(dockerfile, platform)
+	if m.CacheRef != "" {
+		args = append(args, "--import-cache", "type=registry,ref="+m.cacheRefFor(platform))
+	}
+	args = append(args, "--output", "type=oci,dest=/out/image.tar")
+
+	tarball := m.buildkitContainer(source, imageName, platform).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithExec(args, dagger.ContainerWithExecOpts{
+			InsecureRootCapabilities: true,
+		}).
+		File("/out/image.tar")
+
+	return dag.Container(dagger.ContainerOpts{Platform: platform}).Import(tarball)
+}
+
+// exportCache refreshes the registry cache for every configured platform.
+// BuildKit state is kept in a cache volume, so this re-runs a fully cached
+// build and only uploads the cache manifest and missing layers.
+func (m *Docker) exportCache(
+	ctx context.Context,
+	source *dagger.Directory,
+	imageName string,
+	dockerfile string,
+) error {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	for _, platform := range m.getPlatforms() {
+		cacheExport := "type=registry,ref=" + m.cacheRefFor(platform) + ",mode=max"
+		if m.CacheIgnoreErrors {
+			cacheExport += ",ignore-error=true"
+		}
+
+		args := append(m.buildctlArgs(dockerfile, platform), "--export-cache", cacheExport)
+
+		// Cache buster ensures the export runs even if an identical export ran before
+		_, err := m.buildkitContainer(source, imageName, platform).
+			WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
+			WithExec(args, dagger.ContainerWithExecOpts{
+				InsecureRootCapabilities: true,
+			}).
+			Sync(ctx)
+		if err != nil && !m.CacheIgnoreErrors {
+			return fmt.Errorf("failed to export build cache for %s: %w", platform, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,534 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
+)
+
+// manifestMediaTypes are accepted when resolving tags, so registries return
+// image indexes as-is instead of converting them
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// CleanupEntry is a tag deleted (or to be deleted in dry-run mode) by Cleanup()
+type CleanupEntry struct {
+	// Full image reference (e.g., "myacr.azurecr.io/myapp:v1.0.0-dev")
+	Reference string
+	Tag       string
+	Digest    string
+	// Image creation time (RFC 3339), empty when unknown
+	Created string
+	// False in dry-run mode
+	Deleted bool
+}
+
+// Cleanup prunes old tags of an image according to retention rules
+//
+// Lists the tags of the image in every push registry configured via
+// WithRegistry() (or in the given local registry service) and selects the
+// tags matching the patterns for deletion, except:
+//   - the keepLast most recent tags per pattern
+//   - tags younger than keepYoungerThan
+//   - semantic version releases (v1.2.3, v1.2.3-release)
+//   - tags pointing to the same digest as a kept tag, since deleting a
+//     manifest removes every tag pointing to it
+//
+// Tags attaching artifacts to a manifest (cosign signatures, attestations and
+// SBOMs such as sha256-<digest>.sig, and OCI referrers fallback tags) never match
+// the patterns: they are deleted together with the manifest they refer to.
+// Tags whose creation time is unknown are kept when keepYoungerThan is set.
+//
+// Runs in dry-run mode by default. Returns the tags that were (or would be) deleted.
+//
+// +cache="never"
+func (m *Docker) Cleanup(
+	ctx context.Context,
+	// Image name without registry prefix (e.g., "myapp")
+	imageName string,
+	// Tag patterns to prune (glob, e.g., "*-dev", "pr-*"); defaults to every tag
+	// +optional
+	patterns []string,
+	// Number of most recent tags to keep per pattern
+	// +optional
+	// +default=5
+	keepLast int,
+	// Keep tags younger than this age (e.g., "72h", "30d")
+	// +optional
+	keepYoungerThan string,
+	// Only report what would be deleted
+	// +optional
+	// +default=true
+	dryRun bool,
+	// Local registry service (e.g., registry:2) to clean instead of the configured registries
+	// +optional
+	registry *dagger.Service,
+) ([]*CleanupEntry, error) {
+	if err := validateImageName(imageName); err != nil {
+		return nil, fmt.Errorf("invalid image name: %w", err)
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+		}
+	}
+	if keepLast < 0 {
+		return nil, fmt.Errorf("keepLast must not be negative")
+	}
+
+	var minAge time.Duration
+	if keepYoungerThan != "" {
+		var err error
+		if minAge, err = parseAge(keepYoungerThan); err != nil {
+			return nil, err
+		}
+	}
+
+	var clients []*registryClient
+	if registry != nil {
+		endpoint, err := registry.Endpoint(ctx, dagger.ServiceEndpointOpts{Scheme: "http"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve registry service: %w", err)
+		}
+		clients = append(clients, newRegistryClient(ctx, endpoint, "", nil))
+	} else {
+		registries := m.pushRegistries()
+		if len(registries) == 0 {
+			return nil, fmt.Errorf("registry not configured: use WithRegistry() first")
+		}
+		for _, r := range registries {
+			clients = append(clients, newRegistryClient(ctx, r.Host, r.Username, r.Password))
+		}
+	}
+
+	entries := []*CleanupEntry{}
+	for _, client := range clients {
+		pruned, err := client.prune(ctx, imageName, patterns, keepLast, minAge, dryRun)
+		entries = append(entries, pruned...)
+		if err != nil {
+			return nil, fmt.Errorf("cleanup of %s failed: %w", buildRepository(client.host, imageName), err)
+		}
+	}
+
+	return entries, nil
+}
+
+// registryTag is a tag resolved to its manifest digest and creation time
+type registryTag struct {
+	name    string
+	digest  string
+	created time.Time
+}
+
+// artifactTagPattern matches tags attaching an artifact to a manifest: cosign
+// signatures, attestations and SBOMs (sha256-<hex>.sig) and the OCI referrers
+// tag schema fallback (sha256-<hex>)
+var artifactTagPattern = regexp.MustCompile(`^(sha256)-([0-9a-f]{64})(\.[0-9A-Za-z_-]+)?$`)
+
+// artifactSubject returns the digest of the manifest an artifact tag refers
+// to, or an empty string for other tags
+func artifactSubject(tag string) string {
+	matches := artifactTagPattern.FindStringSubmatch(tag)
+	if matches == nil {
+		return ""
+	}
+	return matches[1] + ":" + matches[2]
+}
+
+// prune applies the retention rules to one repository
+func (c *registryClient) prune(
+	ctx context.Context,
+	imageName string,
+	patterns []string,
+	keepLast int,
+	minAge time.Duration,
+	dryRun bool,
+) ([]*CleanupEntry, error) {
+	names, err := c.listTags(ctx, imageName)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]*registryTag, 0, len(names))
+	// Artifact tags by subject digest
+	artifacts := map[string][]*registryTag{}
+	for _, name := range names {
+		digest, err := c.manifestDigest(ctx, imageName, name)
+		if err != nil {
+			return nil, err
+		}
+		if subject := artifactSubject(name); subject != "" {
+			artifacts[subject] = append(artifacts[subject], &registryTag{name: name, digest: digest})
+			continue
+		}
+		created, err := c.imageCreated(ctx, imageName, digest)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, &registryTag{name: name, digest: digest, created: created})
+	}
+
+	selected := selectPrunable(tags, patterns, keepLast, minAge, time.Now())
+
+	// Artifacts go with their subject, after it so a failed deletion leaves
+	// no unsigned image behind
+	var prunable []*registryTag
+	for _, tag := range selected {
+		prunable = append(prunable, tag)
+		prunable = append(prunable, artifacts[tag.digest]...)
+		delete(artifacts, tag.digest)
+	}
+
+	entries := []*CleanupEntry{}
+	deleted := map[string]bool{}
+	for _, tag := range prunable {
+		entry := &CleanupEntry{
+			Reference: buildFullReference(c.host, imageName, tag.name),
+			Tag:       tag.name,
+			Digest:    tag.digest,
+		}
+		if !tag.created.IsZero() {
+			entry.Created = tag.created.Format(time.RFC3339)
+		}
+
+		if !dryRun {
+			// Tags sharing a digest are removed together with the manifest
+			if !deleted[tag.digest] {
+				if err := c.deleteManifest(ctx, imageName, tag.digest); err != nil {
+					return entries, err
+				}
+				deleted[tag.digest] = true
+			}
+			entry.Deleted = true
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// selectPrunable applies the retention rules and returns the tags to delete,
+// oldest first, excluding tags sharing a digest with a kept tag
+func selectPrunable(tags []*registryTag, patterns []string, keepLast int, minAge time.Duration, now time.Time) []*registryTag {
+	// Most recent first
+	tags = slices.Clone(tags)
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].created.After(tags[j].created) })
+
+	// Select candidates, keeping the most recent tags of each pattern
+	candidates := map[string]bool{}
+	kept := map[string]bool{}
+	for _, pattern := range patterns {
+		matched := 0
+		for _, tag := range tags {
+			if ok, _ := path.Match(pattern, tag.name); !ok {
+				continue
+			}
+			matched++
+			if matched <= keepLast {
+				kept[tag.name] = true
+			} else {
+				candidates[tag.name] = true
+			}
+		}
+	}
+
+	// Every tag not selected for deletion protects its digest. The age of a tag
+	// without creation time is unknown, so it is never old enough.
+	protected := map[string]bool{}
+	var selected []*registryTag
+	for _, tag := range tags {
+		switch {
+		case !candidates[tag.name] || kept[tag.name],
+			isReleaseTag(tag.name),
+			minAge > 0 && (tag.created.IsZero() || now.Sub(tag.created) < minAge):
+			protected[tag.digest] = true
+		default:
+			selected = append(selected, tag)
+		}
+	}
+
+	// Oldest first
+	var prunable []*registryTag
+	for i := len(selected) - 1; i >= 0; i-- {
+		if !protected[selected[i].digest] {
+			prunable = append(prunable, selected[i])
+		}
+	}
+	return prunable
+}
+
+// isReleaseTag reports whether a tag is a semantic version release (v1.2.3, v1.2.3-release)
+func isReleaseTag(tag string) bool {
+	matches := semverPattern.FindStringSubmatch(tag)
+	return matches != nil && (matches[4] == "" || strings.EqualFold(matches[4], "release"))
+}
+
+// parseAge parses a Go duration, also accepting a number of days (e.g., "30d")
+func parseAge(age string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %s (e.g., 72h, 30d)", age)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(age)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s (e.g., 72h, 30d)", age)
+	}
+	return d, nil
+}
+
+// registryClient talks to the registry v2 API, answering Basic and Bearer
+// authentication challenges with the configured credentials
+type registryClient struct {
+	host     string
+	baseURL  string
+	username string
+	password string
+	// Error reading the password, reported on the first authentication challenge
+	passwordErr error
+	basic       bool
+	token       string
+	client      *http.Client
+}
+
+// newRegistryClient creates a client for a registry host or http(s):// endpoint
+func newRegistryClient(ctx context.Context, registry, username string, password *dagger.Secret) *registryClient {
+	c := &registryClient{
+		host:     registryHostname(registry),
+		baseURL:  registryURL(registry),
+		username: username,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if c.host == "docker.io" {
+		c.baseURL = "https://registry-1.docker.io"
+	}
+	if password != nil {
+		c.password, c.passwordErr = password.Plaintext(ctx)
+	}
+	return c
+}
+
+// do sends a request, authenticating and retrying once on 401 Unauthorized
+func (c *registryClient) do(ctx context.Context, method, endpoint string, accept []string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		switch {
+		case c.token != "":
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		case c.basic:
+			req.SetBasicAuth(c.username, c.password)
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(ctx, challenge); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// challengeParams matches key="value" pairs of a WWW-Authenticate header
+var challengeParams = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authenticate answers a WWW-Authenticate challenge
+func (c *registryClient) authenticate(ctx context.Context, challenge string) error {
+	if c.passwordErr != nil {
+		return fmt.Errorf("failed to read registry password: %w", c.passwordErr)
+	}
+
+	scheme, params, _ := strings.Cut(challenge, " ")
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if c.username == "" {
+			return fmt.Errorf("%s requires credentials: use WithRegistry()", c.host)
+		}
+		c.basic = true
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication challenge from %s: %q", c.host, challenge)
+	}
+
+	values := map[string]string{}
+	for _, match := range challengeParams.FindAllStringSubmatch(params, -1) {
+		values[match[1]] = match[2]
+	}
+	if values["realm"] == "" {
+		return fmt.Errorf("invalid authentication challenge from %s: %q", c.host, challenge)
+	}
+
+	query := url.Values{}
+	if values["service"] != "" {
+		query.Set("service", values["service"])
+	}
+	if values["scope"] != "" {
+		query.Set("scope", values["scope"])
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, values["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &token); err != nil {
+		return fmt.Errorf("failed to get registry token: %w", err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	return nil
+}
+
+// getJSON fetches a registry API path and decodes the JSON response
+func (c *registryClient) getJSON(ctx context.Context, endpoint string, accept []string, result any) (http.Header, error) {
+	resp, err := c.do(ctx, http.MethodGet, endpoint, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s: %s", resp.Request.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp.Header, json.Unmarshal(body, result)
+}
+
+// listTags returns every tag of a repository, following pagination links
+func (c *registryClient) listTags(ctx context.Context, repository string) ([]string, error) {
+	var tags []string
+	endpoint := c.baseURL + "/v2/" + repository + "/tags/list?n=1000"
+	for endpoint != "" {
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		header, err := c.getJSON(ctx, endpoint, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, page.Tags...)
+
+		// Link: </v2/<name>/tags/list?n=1000&last=x>; rel="next"
+		endpoint = ""
+		if link := header.Get("Link"); strings.Contains(link, `rel="next"`) {
+			start, end := strings.Index(link, "<"), strings.Index(link, ">")
+			if start >= 0 && end > start {
+				next, err := url.Parse(link[start+1 : end])
+				if err != nil {
+					return nil, fmt.Errorf("invalid pagination link: %w", err)
+				}
+				base, _ := url.Parse(c.baseURL)
+				endpoint = base.ResolveReference(next).String()
+			}
+		}
+	}
+	return tags, nil
+}
+
+// manifestDigest resolves a tag to its manifest digest
+func (c *registryClient) manifestDigest(ctx context.Context, repository, tag string) (string, error) {
+	resp, err := c.do(ctx, http.MethodHead, c.baseURL+"/v2/"+repository+"/manifests/"+tag, manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if resp.StatusCode != http.StatusOK || digest == "" {
+		return "", fmt.Errorf("failed to resolve tag %s: %s", tag, resp.Status)
+	}
+	return digest, nil
+}
+
+// imageCreated returns the creation time from the image config. Image indexes
+// use the config of their first manifest. Returns the zero time when unknown.
+func (c *registryClient) imageCreated(ctx context.Context, repository, reference string) (time.Time, error) {
+	var manifest struct {
+		Manifests []struct {
+			Digest string `json:"digest"`
+		} `json:"manifests"`
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+	}
+	if _, err := c.getJSON(ctx, c.baseURL+"/v2/"+repository+"/manifests/"+reference, manifestMediaTypes, &manifest); err != nil {
+		return time.Time{}, err
+	}
+
+	if len(manifest.Manifests) > 0 {
+		return c.imageCreated(ctx, repository, manifest.Manifests[0].Digest)
+	}
+	if manifest.Config.Digest == "" {
+		return time.Time{}, nil
+	}
+
+	var config struct {
+		Created time.Time `json:"created"`
+	}
+	if _, err := c.getJSON(ctx, c.baseURL+"/v2/"+repository+"/blobs/"+manifest.Config.Digest, nil, &config); err != nil {
+		return time.Time{}, err
+	}
+	return config.Created, nil
+}
+
+// deleteManifest deletes a manifest and every tag pointing to it
+func (c *registryClient) deleteManifest(ctx context.Context, repository, digest string) error {
+	resp, err := c.do(ctx, http.MethodDelete, c.baseURL+"/v2/"+repository+"/manifests/"+digest, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("failed to delete %s: %s: %s", digest, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIsReleaseTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"v1.2.3", true},
+		{"1.2.3", true},
+		{"v1.2.3-release", true},
+		{"v1.2.3-RELEASE", true},
+		{"v1.2.3-dev", false},
+		{"v1.2.3-rc.1", false},
+		{"v1.2", false},
+		{"latest", false},
+		{"sha256-0123.sig", false},
+	}
+	for _, tt := range tests {
+		if got := isReleaseTag(tt.tag); got != tt.want {
+			t.Errorf("isReleaseTag(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"72h", 72 * time.Hour, false},
+		{"30d", 30 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"-1d", 0, true},
+		{"-5h", 0, true},
+		{"xd", 0, true},
+		{"week", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.age)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v, error %v", tt.age, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestArtifactSubject(t *testing.T) {
+	hexDigest := strings.Repeat("ab", 32)
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"sha256-" + hexDigest + ".sig", "sha256:" + hexDigest},
+		{"sha256-" + hexDigest + ".att", "sha256:" + hexDigest},
+		{"sha256-" + hexDigest + ".sbom", "sha256:" + hexDigest},
+		{"sha256-" + hexDigest, "sha256:" + hexDigest},
+		{"sha256-" + hexDigest[:10] + ".sig", ""},
+		{"v1.0.0", ""},
+		{"sha256", ""},
+	}
+	for _, tt := range tests {
+		if got := artifactSubject(tt.tag); got != tt.want {
+			t.Errorf("artifactSubject(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestSelectPrunable(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tag := func(name, digest string, age time.Duration) *registryTag {
+		return &registryTag{name: name, digest: digest, created: now.Add(-age)}
+	}
+	tags := []*registryTag{
+		tag("v1.0.0", "sha256:release", 10*day),
+		tag("1-dev", "sha256:1", 4*day),
+		tag("2-dev", "sha256:2", 3*day),
+		tag("3-dev", "sha256:3", 2*day),
+		tag("latest", "sha256:3", 2*day),
+		tag("4-dev", "sha256:4", day),
+		{name: "unknown-dev", digest: "sha256:unknown"},
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		keepLast int
+		minAge   time.Duration
+		want     []string
+	}{
+		{
+			name:     "keep last per pattern, oldest first",
+			patterns: []string{"*-dev"},
+			keepLast: 2,
+			want:     []string{"unknown-dev", "1-dev", "2-dev"},
+		},
+		{
+			name:     "tag sharing a digest with a kept tag is protected",
+			patterns: []string{"*"},
+			keepLast: 2,
+			want:     []string{"unknown-dev", "1-dev", "2-dev"},
+		},
+		{
+			name:     "releases are never pruned",
+			patterns: []string{"v*"},
+			keepLast: 0,
+			want:     nil,
+		},
+		{
+			name:     "young tags and tags of unknown age are kept",
+			patterns: []string{"*-dev"},
+			keepLast: 0,
+			minAge:   3*day + time.Hour,
+			want:     []string{"1-dev"},
+		},
+		{
+			name:     "pattern matching nothing",
+			patterns: []string{"pr-*"},
+			keepLast: 0,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, tag := range selectPrunable(tags, tt.patterns, tt.keepLast, tt.minAge, now) {
+				got = append(got, tag.name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("selectPrunable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCleanupRegistry prunes a registry populated through the v2 API: the
+// registry:2 instance at CLEANUP_TEST_REGISTRY (e.g., http://localhost:5000,
+// started with REGISTRY_STORAGE_DELETE_ENABLED=true) or an in-memory fake of
+// the same API
+func TestCleanupRegistry(t *testing.T) {
+	ctx := context.Background()
+	endpoint := os.Getenv("CLEANUP_TEST_REGISTRY")
+	if endpoint == "" {
+		server := httptest.NewServer(newFakeRegistry())
+		t.Cleanup(server.Close)
+		endpoint = server.URL
+	}
+
+	// Unique repository so the test can run repeatedly against the same registry
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	repository := "cleanup-test-" + hex.EncodeToString(suffix)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	day := 24 * time.Hour
+	release := pushTestImage(t, endpoint, repository, "v1.0.0", "release", now.Add(-10*day))
+	dev1 := pushTestImage(t, endpoint, repository, "1-dev", "1", now.Add(-4*day))
+	pushTestImage(t, endpoint, repository, "2-dev", "2", now.Add(-3*day))
+	pushTestImage(t, endpoint, repository, "3-dev", "3", now.Add(-2*day))
+	pushTestImage(t, endpoint, repository, "latest", "3", now.Add(-2*day))
+	dev4 := pushTestImage(t, endpoint, repository, "4-dev", "4", now.Add(-day))
+
+	// Signatures and referrers fallback tags have no creation time
+	dev1Signature := artifactTag(dev1) + ".sig"
+	pushTestImage(t, endpoint, repository, dev1Signature, "signature 1", time.Time{})
+	pushTestImage(t, endpoint, repository, artifactTag(release)+".sig", "signature release", time.Time{})
+	pushTestImage(t, endpoint, repository, artifactTag(dev4), "referrers 4", time.Time{})
+
+	client := newRegistryClient(ctx, endpoint, "", nil)
+	before, err := client.listTags(ctx, repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(before)
+
+	want := []string{"1-dev", dev1Signature, "2-dev"}
+
+	t.Run("dry run", func(t *testing.T) {
+		entries, err := client.prune(ctx, repository, []string{"*"}, 2, 0, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cleanupTags(entries, false); !slices.Equal(got, want) {
+			t.Errorf("prune() = %v, want %v", got, want)
+		}
+		after, err := client.listTags(ctx, repository)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(after)
+		if !slices.Equal(after, before) {
+			t.Errorf("dry run deleted tags: %v, want %v", after, before)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		entries, err := client.prune(ctx, repository, []string{"*"}, 2, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cleanupTags(entries, true); !slices.Equal(got, want) {
+			t.Errorf("prune() = %v, want %v", got, want)
+		}
+
+		after, err := client.listTags(ctx, repository)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(after)
+		remaining := slices.DeleteFunc(slices.Clone(before), func(tag string) bool { return slices.Contains(want, tag) })
+		if !slices.Equal(after, remaining) {
+			t.Errorf("remaining tags = %v, want %v", after, remaining)
+		}
+	})
+}
+
+// cleanupTags returns the tags of cleanup entries, checking their Deleted flag
+func cleanupTags(entries []*CleanupEntry, deleted bool) []string {
+	var tags []string
+	for _, entry := range entries {
+		if entry.Deleted != deleted {
+			return []string{fmt.Sprintf("%s: Deleted=%v", entry.Tag, entry.Deleted)}
+		}
+		tags = append(tags, entry.Tag)
+	}
+	return tags
+}
+
+// artifactTag returns the cosign tag prefix of a digest ("sha256:x" -> "sha256-x")
+func artifactTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1)
+}
+
+// pushTestImage pushes a single-layer image whose config records the creation
+// time (omitted when zero) and returns its manifest digest. Images with the same
+// layer and creation time share their manifest.
+func pushTestImage(t *testing.T, endpoint, repository, tag, layerContent string, created time.Time) string {
+	t.Helper()
+
+	config := map[string]any{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs":       map[string]any{"type": "layers", "diff_ids": []string{}},
+	}
+	if !created.IsZero() {
+		config["created"] = created.Format(time.RFC3339)
+	}
+	configJSON, _ := json.Marshal(config)
+	layer := []byte(layerContent)
+
+	manifest, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]any{
+			"mediaType": "application/vnd.oci.image.config.v1+json",
+			"digest":    pushTestBlob(t, endpoint, repository, configJSON),
+			"size":      len(configJSON),
+		},
+		"layers": []map[string]any{{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip",
+			"digest":    pushTestBlob(t, endpoint, repository, layer),
+			"size":      len(layer),
+		}},
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, endpoint+"/v2/"+repository+"/manifests/"+tag, bytes.NewReader(manifest))
+	req.Header.Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+	resp := doTestRequest(t, req, http.StatusCreated)
+	return resp.Header.Get("Docker-Content-Digest")
+}
+
+// pushTestBlob uploads a blob in a single request and returns its digest
+func pushTestBlob(t *testing.T, endpoint, repository string, content []byte) string {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodPost, endpoint+"/v2/"+repository+"/blobs/uploads/", nil)
+	location := doTestRequest(t, req, http.StatusAccepted).Header.Get("Location")
+
+	digest := sha256Digest(content)
+	uploadURL, err := resolveTestURL(endpoint, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	separator := "?"
+	if strings.Contains(uploadURL, "?") {
+		separator = "&"
+	}
+	req, _ = http.NewRequest(http.MethodPut, uploadURL+separator+"digest="+digest, bytes.NewReader(content))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	doTestRequest(t, req, http.StatusCreated)
+	return digest
+}
+
+// resolveTestURL resolves a Location header against the registry endpoint
+func resolveTestURL(endpoint, location string) (string, error) {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return location, nil
+	}
+	if !strings.HasPrefix(location, "/") {
+		return "", fmt.Errorf("unexpected upload location %q", location)
+	}
+	return strings.TrimSuffix(endpoint, "/") + location, nil
+}
+
+// doTestRequest sends a request and fails the test on an unexpected status
+func doTestRequest(t *testing.T, req *http.Request, status int) *http.Response {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != status {
+		t.Fatalf("%s %s returned %s: %s", req.Method, req.URL.Path, resp.Status, body)
+	}
+	return resp
+}
+
+func sha256Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeRegistry implements the subset of the registry v2 API used by Cleanup()
+// and the tests: blob uploads, manifests by tag or digest, tag listing and
+// manifest deletion (which removes the tags pointing to the manifest)
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	// Tags per repository
+	tags map[string]map[string]string
+}
+
+var (
+	fakeUploadPath = regexp.MustCompile(`^/v2/(.+)/blobs/uploads/([^/]*)$`)
+	fakeAPIPath    = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs)/([^/]+)$`)
+	fakeTagsPath   = regexp.MustCompile(`^/v2/(.+)/tags/list$`)
+)
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+		tags:      map[string]map[string]string{},
+	}
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m := fakeUploadPath.FindStringSubmatch(req.URL.Path); m != nil {
+		switch req.Method {
+		case http.MethodPost:
+			w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%d", m[1], len(r.blobs)))
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodPut:
+			content, _ := io.ReadAll(req.Body)
+			digest := req.URL.Query().Get("digest")
+			if digest != sha256Digest(content) {
+				http.Error(w, "digest mismatch", http.StatusBadRequest)
+				return
+			}
+			r.blobs[digest] = content
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if m := fakeTagsPath.FindStringSubmatch(req.URL.Path); m != nil {
+		tags := []string{}
+		for tag := range r.tags[m[1]] {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": m[1], "tags": tags})
+		return
+	}
+
+	m := fakeAPIPath.FindStringSubmatch(req.URL.Path)
+	if m == nil {
+		http.NotFound(w, req)
+		return
+	}
+	repository, kind, reference := m[1], m[2], m[3]
+
+	if kind == "blobs" {
+		content, ok := r.blobs[reference]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(content)
+		return
+	}
+
+	if r.tags[repository] == nil {
+		r.tags[repository] = map[string]string{}
+	}
+	digest := reference
+	if !strings.HasPrefix(reference, "sha256:") {
+		digest = r.tags[repository][reference]
+	}
+
+	switch req.Method {
+	case http.MethodPut:
+		content, _ := io.ReadAll(req.Body)
+		digest = sha256Digest(content)
+		r.manifests[digest] = content
+		if !strings.HasPrefix(reference, "sha256:") {
+			r.tags[repository][reference] = digest
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		content, ok := r.manifests[digest]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		w.Header().Set("Docker-Content-Digest", digest)
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+	case http.MethodDelete:
+		if _, ok := r.manifests[digest]; !ok || digest != reference {
+			http.NotFound(w, req)
+			return
+		}
+		delete(r.manifests, digest)
+		for tag, d := range r.tags[repository] {
+			if d == digest {
+				delete(r.tags[repository], tag)
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
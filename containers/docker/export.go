@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// embedTagsScript rewrites the tarball index so every configured reference
+// is recorded (containerd/ctr and skopeo read index.json, docker load reads manifest.json)
+const embedTagsScript = `set -e
+mkdir /image
+tar -xf /in.tar -C /image
+cd /image
+jq --argjson refs "$IMAGE_REFS" '.manifests |= [.[0] as $m | $refs[] | $m + {annotations: (($m.annotations // {}) + {"io.containerd.image.name": ., "org.opencontainers.image.ref.name": (split(":") | last)})}]' index.json > /tmp/index.json
+mv /tmp/index.json index.json
+if [ -f manifest.json ]; then
+  jq --argjson refs "$IMAGE_REFS" '.[0].RepoTags = $refs' manifest.json > /tmp/manifest.json
+  mv /tmp/manifest.json manifest.json
+fi
+tar -cf /out.tar -C /image .
+`
+
+// tarballPlatformsScript prints the platforms contained in an OCI tarball, one per line
+const tarballPlatformsScript = `set -e
+mkdir /image
+tar -xf /in.tar -C /image
+cd /image
+blob() { echo "blobs/$(echo "$1" | tr ':' '/')"; }
+desc=$(jq -c '.manifests[0]' index.json)
+digest=$(echo "$desc" | jq -r '.digest')
+case "$(echo "$desc" | jq -r '.mediaType')" in
+  *index*|*manifest.list*)
+    jq -r '.manifests[] | select(.platform.os != "unknown") | "\(.platform.os)/\(.platform.architecture)\(if .platform.variant then "/" + .platform.variant else "" end)"' "$(blob "$digest")"
+    ;;
+  *)
+    config=$(jq -r '.config.digest' "$(blob "$digest")")
+    jq -r '"\(.os)/\(.architecture)\(if .variant then "/" + .variant else "" end)"' "$(blob "$config")"
+    ;;
+esac
+`
+
+// Export builds the image and returns it as a tarball for air-gapped delivery
+//
+// All configured platforms are included in a single tarball and every
+// configured tag of every push registry is embedded as an image reference.
+// The tarball can be loaded with "docker load" (single platform), imported
+// with containerd/skopeo, or published later with PushTarball().
+func (m *Docker) Export(
+	ctx context.Context,
+	// Directory containing Dockerfile and build context
+	// +ignore=[".git", "**/.gitignore"]
+	source *dagger.Directory,
+	// Image name without registry prefix (e.g., "myapp")
+	imageName string,
+	// Path to Dockerfile relative to source
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Tarball media types ("oci" or "docker")
+	// +optional
+	// +default="oci"
+	format string,
+) (*dagger.File, error) {
+	var mediaTypes dagger.ImageMediaTypes
+	switch format {
+	case "", "oci":
+		mediaTypes = dagger.ImageMediaTypesOcimediaTypes
+	case "docker":
+		mediaTypes = dagger.ImageMediaTypesDockerMediaTypes
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s (supported: oci, docker)", format)
+	}
+
+	variants, err := m.BuildPlatforms(ctx, source, imageName, dockerfile)
+	if err != nil {
+		return nil, fmt.Errorf("build failed: %w", err)
+	}
+
+	tarball := variants[0].AsTarball(dagger.ContainerAsTarballOpts{
+		PlatformVariants: variants[1:],
+		MediaTypes:       mediaTypes,
+	})
+
+	// Embed every configured reference in the tarball
+	var refs []string
+	for _, registry := range m.pushRegistries() {
+		for _, tag := range m.getDefaultTags() {
+			refs = append(refs, buildFullReference(registry.Host, imageName, tag))
+		}
+	}
+	if len(refs) == 0 {
+		// No registry configured: embed local references
+		for _, tag := range m.getDefaultTags() {
+			refs = append(refs, buildFullReference("", imageName, tag))
+		}
+	}
+	refsJSON, err := json.Marshal(refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image references: %w", err)
+	}
+
+	return jqContainer().
+		WithMountedFile("/in.tar", tarball).
+		WithEnvVariable("IMAGE_REFS", string(refsJSON)).
+		WithExec([]string{"sh", "-c", embedTagsScript}).
+		File("/out.tar"), nil
+}
+
+// Load imports an image tarball produced by Export() as a container
+//
+// For multi-platform tarballs, the variant matching the platform is loaded.
+func (m *Docker) Load(
+	// Image tarball from Export()
+	tarball *dagger.File,
+	// Platform to load (defaults to the first configured platform)
+	// +optional
+	platform dagger.Platform,
+) *dagger.Container {
+	if platform == "" {
+		platform = m.getPlatforms()[0]
+	}
+	return dag.Container(dagger.ContainerOpts{Platform: platform}).Import(tarball)
+}
+
+// PushTarball publishes an image tarball produced by Export() to the registry
+//
+// Every platform contained in the tarball is published as a single image index
+// under all configured tags, like Push().
+// Returns the image digest.
+func (m *Docker) PushTarball(
+	ctx context.Context,
+	// Image tarball from Export()
+	tarball *dagger.File,
+	// Image name without registry prefix (e.g., "myapp")
+	imageName string,
+) (string, error) {
+	output, err := jqContainer().
+		WithMountedFile("/in.tar", tarball).
+		WithExec([]string{"sh", "-c", tarballPlatformsScript}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read tarball platforms: %w", err)
+	}
+
+	var variants []*dagger.Container
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		variants = append(variants, m.Load(tarball, dagger.Platform(line)))
+	}
+	if len(variants) == 0 {
+		return "", fmt.Errorf("no image found in tarball")
+	}
+
+	return m.Push(ctx, variants[0], imageName, variants[1:])
+}
+
+// jqContainer creates a container with jq and tar for manipulating image tarballs
+func jqContainer() *dagger.Container {
+	return dag.Container().
+		From("alpine:3.20").
+		WithExec([]string{"apk", "add", "--no-cache", "jq", "tar"})
+}
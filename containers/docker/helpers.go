@@ -1,11 +1,85 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
 )
 
+// defaultPlatform is used when no platform has been configured via WithPlatform()
+const defaultPlatform dagger.Platform = "linux/amd64"
+
+// clone returns a deep copy of the Docker configuration (immutable pattern)
+func (m *Docker) clone() *Docker {
+	newRegistries := make([]DockerRegistry, len(m.Registries))
+	copy(newRegistries, m.Registries)
+
+	newBuildArgs := make([]DockerBuildArg, len(m.BuildArgs))
+	copy(newBuildArgs, m.BuildArgs)
+
+	newBuildSecrets := make([]DockerBuildSecret, len(m.BuildSecrets))
+	copy(newBuildSecrets, m.BuildSecrets)
+
+	newTags := make([]string, len(m.Tags))
+	copy(newTags, m.Tags)
+
+	newTagChannels := make([]string, len(m.TagChannels))
+	copy(newTagChannels, m.TagChannels)
+
+	newPlatforms := make([]dagger.Platform, len(m.Platforms))
+	copy(newPlatforms, m.Platforms)
+
+	newLabels := make([]DockerKeyValue, len(m.Labels))
+	copy(newLabels, m.Labels)
+
+	newAnnotations := make([]DockerKeyValue, len(m.Annotations))
+	copy(newAnnotations, m.Annotations)
+
+	return &Docker{
+		Registries:        newRegistries,
+		BuildArgs:         newBuildArgs,
+		BuildSecrets:      newBuildSecrets,
+		Tags:              newTags,
+		Target:            m.Target,
+		Platforms:         newPlatforms,
+		TagChannels:       newTagChannels,
+		TagMoveLatest:     m.TagMoveLatest,
+		TagFloating:       m.TagFloating,
+		TagBuildMetadata:  m.TagBuildMetadata,
+		CacheRef:          m.CacheRef,
+		CacheIgnoreErrors: m.CacheIgnoreErrors,
+		SbomFormat:        m.SbomFormat,
+		ProvenanceBuilder: m.ProvenanceBuilder,
+		ScanDB:            m.ScanDB,
+		ScanSeverity:      m.ScanSeverity,
+		ScanAllowlist:     m.ScanAllowlist,
+		SigningKey:        m.SigningKey,
+		SigningPassword:   m.SigningPassword,
+		SigningTlog:       m.SigningTlog,
+		Labels:            newLabels,
+		Annotations:       newAnnotations,
+		GitRevision:       m.GitRevision,
+		GitSource:         m.GitSource,
+		GitCommitTime:     m.GitCommitTime,
+	}
+}
+
+// setKeyValue returns a copy of items with key set to value, replacing any existing entry
+func setKeyValue(items []DockerKeyValue, key, value string) []DockerKeyValue {
+	result := make([]DockerKeyValue, 0, len(items)+1)
+	for _, item := range items {
+		if item.Key != key {
+			result = append(result, item)
+		}
+	}
+	return append(result, DockerKeyValue{Key: key, Value: value})
+}
+
 // validateImageName ensures image name follows Docker conventions
 func validateImageName(imageName string) error {
 	if imageName == "" {
@@ -22,48 +96,246 @@ func validateImageName(imageName string) error {
 
 // buildFullReference constructs the complete image reference
 func buildFullReference(registryHost, imageName, tag string) string {
+	return fmt.Sprintf("%s:%s", buildRepository(registryHost, imageName), tag)
+}
+
+// buildRepository constructs the image repository without tag
+func buildRepository(registryHost, imageName string) string {
 	if registryHost == "" {
-		return fmt.Sprintf("%s:%s", imageName, tag)
+		return imageName
+	}
+	return fmt.Sprintf("%s/%s", registryHost, imageName)
+}
+
+// registryAuthScript writes a Docker config.json from the numbered
+// REGISTRY_HOST_<n>/REGISTRY_USERNAME_<n>/REGISTRY_PASSWORD_<n> variables and
+// runs the given command. The config lives in a temporary mount so
+// credentials are never persisted in the container filesystem.
+const registryAuthScript = `#!/bin/sh
+set -e
+mkdir -p "$DOCKER_CONFIG"
+entries=""
+i=0
+while eval "[ -n \"\${REGISTRY_HOST_$i:-}\" ]"; do
+  eval "host=\$REGISTRY_HOST_$i user=\$REGISTRY_USERNAME_$i pass=\$REGISTRY_PASSWORD_$i"
+  auth=$(printf '%s:%s' "$user" "$pass" | base64 | tr -d '\n')
+  entries="$entries${entries:+,}\"$host\":{\"auth\":\"$auth\"}"
+  i=$((i + 1))
+done
+printf '{"auths":{%s}}\n' "$entries" > "$DOCKER_CONFIG/config.json"
+exec "$@"
+`
+
+// registryCredential holds credentials for one registry host
+type registryCredential struct {
+	host     string
+	username string
+	password *dagger.Secret
+}
+
+// registryCredentials returns the credentials configured via WithRegistry()
+func (m *Docker) registryCredentials() []registryCredential {
+	creds := make([]registryCredential, 0, len(m.Registries))
+	for _, r := range m.Registries {
+		if r.Username == "" || r.Password == nil {
+			continue
+		}
+		creds = append(creds, registryCredential{
+			host:     r.Host,
+			username: r.Username,
+			password: r.Password,
+		})
+	}
+	return creds
+}
+
+// pushRegistries returns the registries images are pushed to
+func (m *Docker) pushRegistries() []DockerRegistry {
+	var registries []DockerRegistry
+	for _, r := range m.Registries {
+		if !r.PullOnly {
+			registries = append(registries, r)
+		}
+	}
+	return registries
+}
+
+// primaryRegistryHost returns the first push registry, used for image labels
+func (m *Docker) primaryRegistryHost() string {
+	registries := m.pushRegistries()
+	if len(registries) == 0 {
+		return ""
+	}
+	return registries[0].Host
+}
+
+// withRegistryAuth attaches every configured registry credential to a container
+func (m *Docker) withRegistryAuth(container *dagger.Container) *dagger.Container {
+	for _, cred := range m.registryCredentials() {
+		container = container.WithRegistryAuth(cred.host, cred.username, cred.password)
+	}
+	return container
+}
+
+// withRegistryAuthWrapper installs /usr/local/bin/with-registry-auth in a tool
+// container. Prefix commands with it so registry-aware tools (buildctl, oras,
+// cosign, ...) authenticate with the credentials set via WithRegistry() and
+// any extra credentials (e.g., a source registry).
+func (m *Docker) withRegistryAuthWrapper(
+	container *dagger.Container,
+	extra ...registryCredential,
+) *dagger.Container {
+	container = container.
+		WithNewFile("/usr/local/bin/with-registry-auth", registryAuthScript, dagger.ContainerWithNewFileOpts{
+			Permissions: 0o755,
+		}).
+		WithMountedTemp("/run/docker-config").
+		WithEnvVariable("DOCKER_CONFIG", "/run/docker-config")
+
+	for i, cred := range append(m.registryCredentials(), extra...) {
+		host := cred.host
+		if host == "docker.io" {
+			// Docker Hub credentials are keyed by the legacy index URL
+			host = "https://index.docker.io/v1/"
+		}
+		container = container.
+			WithEnvVariable(fmt.Sprintf("REGISTRY_HOST_%d", i), host).
+			WithEnvVariable(fmt.Sprintf("REGISTRY_USERNAME_%d", i), cred.username).
+			WithSecretVariable(fmt.Sprintf("REGISTRY_PASSWORD_%d", i), cred.password)
+	}
+
+	return container
+}
+
+// digestReference converts a reference returned by Publish()
+// (e.g., "registry/app:v1@sha256:abc") into a digest reference ("registry/app@sha256:abc")
+func digestReference(published string) string {
+	repo, digest, found := strings.Cut(published, "@")
+	if !found {
+		return published
+	}
+
+	// Strip the tag, keeping any registry port (e.g., "localhost:5000/app:v1")
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
 	}
-	return fmt.Sprintf("%s/%s:%s", registryHost, imageName, tag)
+	return repo + "@" + digest
 }
 
-// getDefaultTags returns configured tags or "latest" if none
+// orasContainer creates a container with the ORAS CLI and registry authentication
+func (m *Docker) orasContainer() *dagger.Container {
+	return m.withRegistryAuthWrapper(
+		dag.Container().
+			From("alpine:3.20").
+			WithExec([]string{"apk", "add", "--no-cache", "oras-cli"}),
+	)
+}
+
+// yamlToJSON converts a YAML file to JSON so it can be decoded with encoding/json
+func yamlToJSON(ctx context.Context, file *dagger.File) (string, error) {
+	return dag.Container().
+		From("mikefarah/yq:4.44.3").
+		WithMountedFile("/tmp/input.yaml", file).
+		WithExec([]string{"yq", "--output-format", "json", "/tmp/input.yaml"}).
+		Stdout(ctx)
+}
+
+// getDefaultTags returns configured tags expanded with the tag policy, or "latest" if none
 func (m *Docker) getDefaultTags() []string {
 	if len(m.Tags) == 0 {
 		return []string{"latest"}
 	}
-	return m.Tags
-}
 
-// parseSemanticVersion parses a semantic version and returns all applicable tags.
-// For non-semver tags, returns just the original tag.
-//
-// Tagging strategy:
-//   - v1.0.0-dev   -> v1.0.0-dev, v1.0.0-dev, v1.0-dev, v1-dev, dev
-//   - v1.0.0-dev.1 -> v1.0.0-dev.1, v1.0.0-dev, v1.0-dev, v1-dev, dev
-//   - v1.0.0-rc1   -> v1.0.0-rc1, v1.0.0-rc, v1.0-rc, v1-rc, rc
-//   - v1.0.0-release -> v1.0.0-release, v1.0.0, v1.0, v1, release, latest
-//   - v1.0.0       -> v1.0.0, v1.0, v1, latest
-func parseSemanticVersion(version string) []string {
 	var tags []string
+	for _, tag := range m.Tags {
+		for _, expanded := range m.expandTag(tag) {
+			if !slices.Contains(tags, expanded) {
+				tags = append(tags, expanded)
+			}
+		}
+	}
+	return tags
+}
+
+// getPlatforms returns configured platforms or linux/amd64 if none
+func (m *Docker) getPlatforms() []dagger.Platform {
+	if len(m.Platforms) == 0 {
+		return []dagger.Platform{defaultPlatform}
+	}
+	return m.Platforms
+}
+
+// commitTime converts a git commit time (git show --format=%cI) to UTC RFC 3339
+func commitTime(output string) (string, error) {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(output))
+	if err != nil {
+		return "", fmt.Errorf("invalid commit time %q: %w", strings.TrimSpace(output), err)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// semverPattern matches semantic versions: v1.2.3, v1.2.3-suffix or v1.2.3+metadata.
+// Any suffix is accepted (e.g., "rc_1"), see expandTag() for invalid tag characters.
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([^+]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// isSemanticVersion reports whether a tag is a semantic version
+func isSemanticVersion(version string) bool {
+	return semverPattern.MatchString(version)
+}
+
+// invalidTagChars matches characters not allowed in Docker tags
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
 
-	// Always add the exact version
-	tags = append(tags, version)
+// invalidVersionChars matches characters not allowed in Docker tags, keeping case
+var invalidVersionChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
 
-	// Regex to parse semantic version: v1.2.3 or v1.2.3-suffix
-	re := regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-(.+))?$`)
-	matches := re.FindStringSubmatch(version)
+// sanitizeTag converts an arbitrary string (e.g., a branch name) into a valid Docker tag.
+// For example, "feature/New-Login" becomes "feature-new-login".
+func sanitizeTag(value string) string {
+	tag := invalidTagChars.ReplaceAllString(strings.ToLower(value), "-")
+	tag = strings.TrimLeft(tag, ".-")
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
+
+// expandTag expands a semantic version into all applicable tags according to
+// the tag policy (see WithTagPolicy()). For non-semver tags, returns just the
+// original tag.
+//
+// Tagging strategy with the default policy (channels dev and rc, floating tags,
+// latest moved, build metadata stripped):
+//   - v1.0.0-dev     -> v1.0.0-dev, v1.0-dev, v1-dev, dev
+//   - v1.0.0-dev.1   -> v1.0.0-dev.1, v1.0.0-dev, v1.0-dev, v1-dev, dev
+//   - v1.0.0-rc1     -> v1.0.0-rc1, v1.0.0-rc, v1.0-rc, v1-rc, rc
+//   - v1.0.0-beta.2  -> v1.0.0-beta.2 (beta is not a configured channel)
+//   - v1.0.0-release -> v1.0.0-release, v1.0.0, v1.0, v1, release, latest
+//   - v1.0.0         -> v1.0.0, v1.0, v1, latest
+//   - v1.0.0+abc123  -> v1.0.0, v1.0, v1, latest
+//   - v1.0.0-rc/1    -> v1.0.0-rc-1, v1.0.0-rc, v1.0-rc, v1-rc, rc
+func (m *Docker) expandTag(version string) []string {
+	matches := semverPattern.FindStringSubmatch(version)
 
 	if matches == nil {
 		// Not a valid semver, return just the original tag
-		return tags
+		return []string{version}
 	}
 
 	major := matches[1]
 	minor := matches[2]
 	patch := matches[3]
 	prerelease := matches[4]
+	metadata := matches[5]
+
+	// Docker tags cannot contain "+": drop build metadata or keep it as a "_" suffix.
+	// Other invalid characters of the suffix (e.g., "/") are replaced with "-".
+	exact, _, _ := strings.Cut(version, "+")
+	exact = invalidVersionChars.ReplaceAllString(exact, "-")
+	tags := []string{exact}
+	if metadata != "" && m.TagBuildMetadata == "suffix" {
+		tags[0] = exact + "_" + metadata
+	}
 
 	// Determine the version prefix (with or without 'v')
 	prefix := ""
@@ -76,23 +348,45 @@ func parseSemanticVersion(version string) []string {
 	minorTag := prefix + major + "." + minor
 	majorTag := prefix + major
 
-	// Handle prerelease suffixes
-	if prerelease != "" {
-		prereleaseLower := strings.ToLower(prerelease)
-
-		if strings.HasPrefix(prereleaseLower, "dev") {
-			// Dev tags: v1.0.0-dev, v1.0-dev, v1-dev, dev
-			tags = append(tags, patchTag+"-dev", minorTag+"-dev", majorTag+"-dev", "dev")
-		} else if strings.HasPrefix(prereleaseLower, "rc") {
-			// RC tags: v1.0.0-rc, v1.0-rc, v1-rc, rc
-			tags = append(tags, patchTag+"-rc", minorTag+"-rc", majorTag+"-rc", "rc")
-		} else if prereleaseLower == "release" {
-			// Release tags: v1.0.0, v1.0, v1, release, latest
-			tags = append(tags, patchTag, minorTag, majorTag, "release", "latest")
+	// Stable releases have no prerelease suffix or the "release" suffix
+	if prerelease == "" || strings.EqualFold(prerelease, "release") {
+		if prerelease != "" {
+			tags = append(tags, patchTag)
+		}
+		if m.TagFloating {
+			tags = append(tags, minorTag, majorTag)
+		}
+		if m.TagMoveLatest {
+			if prerelease != "" {
+				tags = append(tags, "release")
+			}
+			tags = append(tags, "latest")
+		}
+		return tags
+	}
+
+	// Prereleases of a configured channel: v1.0.0-rc, v1.0-rc, v1-rc, rc
+	// (the longest matching channel wins, e.g., "rc" for "rc1")
+	channel := ""
+	prereleaseLower := strings.ToLower(prerelease)
+	for _, c := range m.TagChannels {
+		if strings.HasPrefix(prereleaseLower, c) && len(c) > len(channel) {
+			channel = c
 		}
-	} else {
-		// No prerelease suffix = stable release: v1.0, v1, latest
-		tags = append(tags, minorTag, majorTag, "latest")
+	}
+	if channel == "" {
+		return tags
+	}
+
+	if m.TagFloating {
+		for _, tag := range []string{patchTag + "-" + channel, minorTag + "-" + channel, majorTag + "-" + channel} {
+			if tag != tags[0] {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	if m.TagMoveLatest {
+		tags = append(tags, channel)
 	}
 
 	return tags
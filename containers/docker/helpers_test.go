@@ -0,0 +1,77 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpandTag(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []string
+	}{
+		{"v1.0.0-dev", []string{"v1.0.0-dev", "v1.0-dev", "v1-dev", "dev"}},
+		{"v1.0.0-dev.1", []string{"v1.0.0-dev.1", "v1.0.0-dev", "v1.0-dev", "v1-dev", "dev"}},
+		{"v1.0.0-rc1", []string{"v1.0.0-rc1", "v1.0.0-rc", "v1.0-rc", "v1-rc", "rc"}},
+		{"v1.0.0-rc_1", []string{"v1.0.0-rc_1", "v1.0.0-rc", "v1.0-rc", "v1-rc", "rc"}},
+		{"v1.0.0-rc/1", []string{"v1.0.0-rc-1", "v1.0.0-rc", "v1.0-rc", "v1-rc", "rc"}},
+		{"v1.0.0-beta.2", []string{"v1.0.0-beta.2"}},
+		{"v1.0.0-release", []string{"v1.0.0-release", "v1.0.0", "v1.0", "v1", "release", "latest"}},
+		{"v1.0.0", []string{"v1.0.0", "v1.0", "v1", "latest"}},
+		{"1.2.3", []string{"1.2.3", "1.2", "1", "latest"}},
+		{"v1.0.0+abc123", []string{"v1.0.0", "v1.0", "v1", "latest"}},
+		{"v1.0.0-rc.1+abc123", []string{"v1.0.0-rc.1", "v1.0.0-rc", "v1.0-rc", "v1-rc", "rc"}},
+		{"main", []string{"main"}},
+		{"v1.0", []string{"v1.0"}},
+	}
+	for _, tt := range tests {
+		if got := New().expandTag(tt.version); !slices.Equal(got, tt.want) {
+			t.Errorf("expandTag(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestExpandTagPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*Docker)
+		version   string
+		want      []string
+	}{
+		{"no floating tags", func(m *Docker) { m.TagFloating = false }, "v1.2.3", []string{"v1.2.3", "latest"}},
+		{"latest not moved", func(m *Docker) { m.TagMoveLatest = false }, "v1.2.3", []string{"v1.2.3", "v1.2", "v1"}},
+		{"build metadata suffix", func(m *Docker) { m.TagBuildMetadata = "suffix" }, "v1.2.3+abc123", []string{"v1.2.3_abc123", "v1.2", "v1", "latest"}},
+		{"custom channel", func(m *Docker) { m.TagChannels = []string{"beta"} }, "v1.0.0-beta.2", []string{"v1.0.0-beta.2", "v1.0.0-beta", "v1.0-beta", "v1-beta", "beta"}},
+		{"longest channel wins", func(m *Docker) { m.TagChannels = []string{"r", "rc"} }, "v1.0.0-rc1", []string{"v1.0.0-rc1", "v1.0.0-rc", "v1.0-rc", "v1-rc", "rc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			tt.configure(m)
+			if got := m.expandTag(tt.version); !slices.Equal(got, tt.want) {
+				t.Errorf("expandTag(%q) = %v, want %v", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeTag(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"main", "main"},
+		{"feature/New-Login", "feature-new-login"},
+		{"release/v1.2", "release-v1.2"},
+		{"fix__typo", "fix__typo"},
+		{"-leading.dash", "leading.dash"},
+		{"feat//many   spaces", "feat-many-spaces"},
+		{strings.Repeat("a", 200), strings.Repeat("a", 128)},
+	}
+	for _, tt := range tests {
+		if got := sanitizeTag(tt.value); got != tt.want {
+			t.Errorf("sanitizeTag(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// diveImage analyses image layers and wasted space
+const diveImage = "wagoodman/dive:v0.12.0"
+
+// InspectReport is the result of an image size analysis
+type InspectReport struct {
+	// Total image size in bytes
+	Size int
+	// Bytes wasted by files duplicated or removed in later layers
+	WastedBytes int
+	// Dive efficiency score (1.0 means no wasted space)
+	Efficiency float64
+	// Layers from base to top
+	Layers []*LayerInfo
+	// Files wasting the most space, largest first
+	WastedFiles []*WastedFile
+	// Baseline image size in bytes (0 when no baseline was given)
+	BaselineSize int
+	// Size growth vs. the baseline in percent
+	Growth float64
+}
+
+// LayerInfo describes a single image layer
+type LayerInfo struct {
+	Index   int
+	Digest  string
+	Size    int
+	Command string
+}
+
+// WastedFile is a file stored more than once across layers
+type WastedFile struct {
+	Path  string
+	Count int
+	Size  int
+}
+
+// Inspect reports image size, per-layer size and wasted space
+//
+// Analyses either a built container or a pushed image reference with dive.
+// Pushed references are pulled with the credentials configured via WithRegistry().
+// When maxSize or maxGrowth are set, an error is returned if the image exceeds
+// the size budget or grew more than the allowed percentage vs. the baseline.
+func (m *Docker) Inspect(
+	ctx context.Context,
+	// Built container from Build()
+	// +optional
+	container *dagger.Container,
+	// Pushed image reference (e.g., "myacr.azurecr.io/myapp:v1.2.3")
+	// +optional
+	reference string,
+	// Maximum image size (e.g., "250MB", "1.5GiB" or a number of bytes)
+	// +optional
+	maxSize string,
+	// Baseline image reference to compare against (e.g., "myacr.azurecr.io/myapp:latest")
+	// +optional
+	baseline string,
+	// Maximum size growth vs. the baseline in percent (0 disables the check)
+	// +optional
+	maxGrowth float64,
+) (*InspectReport, error) {
+	switch {
+	case container == nil && reference == "":
+		return nil, fmt.Errorf("either container or reference is required")
+	case container != nil && reference != "":
+		return nil, fmt.Errorf("container and reference are mutually exclusive")
+	case maxGrowth > 0 && baseline == "":
+		return nil, fmt.Errorf("maxGrowth requires a baseline reference")
+	}
+
+	var budget int
+	if maxSize != "" {
+		var err error
+		if budget, err = parseSize(maxSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if container == nil {
+		container = m.pullImage(reference)
+	}
+
+	report, err := analyseImage(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseline != "" {
+		base, err := analyseImage(ctx, m.pullImage(baseline))
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyse baseline %s: %w", baseline, err)
+		}
+		report.BaselineSize = base.Size
+		if base.Size > 0 {
+			report.Growth = float64(report.Size-base.Size) / float64(base.Size) * 100
+		}
+	}
+
+	if budget > 0 && report.Size > budget {
+		return nil, fmt.Errorf("image size %s exceeds budget of %s\n\n%s",
+			formatSize(report.Size), formatSize(budget), report.summary())
+	}
+	if maxGrowth > 0 && report.Growth > maxGrowth {
+		return nil, fmt.Errorf("image grew %.1f%% vs. %s (%s -> %s), more than the allowed %.1f%%\n\n%s",
+			report.Growth, baseline, formatSize(report.BaselineSize), formatSize(report.Size), maxGrowth, report.summary())
+	}
+
+	return report, nil
+}
+
+// pullImage returns a pushed image for the first configured platform
+func (m *Docker) pullImage(reference string) *dagger.Container {
+	container := dag.Container(dagger.ContainerOpts{Platform: m.getPlatforms()[0]})
+	return m.withRegistryAuth(container).From(reference)
+}
+
+// analyseImage runs dive against the container image, exported as a Docker
+// archive as expected by dive's docker-archive source
+func analyseImage(ctx context.Context, container *dagger.Container) (*InspectReport, error) {
+	tarball := container.AsTarball(dagger.ContainerAsTarballOpts{
+		MediaTypes: dagger.ImageMediaTypesDockerMediaTypes,
+	})
+	output, err := dag.Container().
+		From(diveImage).
+		WithMountedFile("/image.tar", tarball).
+		WithExec([]string{"dive", "--source", "docker-archive", "/image.tar", "--json", "/tmp/dive.json"}).
+		File("/tmp/dive.json").
+		Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("image analysis failed: %w", err)
+	}
+
+	var result struct {
+		Layer []struct {
+			Index     int    `json:"index"`
+			DigestID  string `json:"digestId"`
+			SizeBytes int    `json:"sizeBytes"`
+			Command   string `json:"command"`
+		} `json:"layer"`
+		Image struct {
+			SizeBytes        int     `json:"sizeBytes"`
+			InefficientBytes int     `json:"inefficientBytes"`
+			EfficiencyScore  float64 `json:"efficiencyScore"`
+			FileReference    []struct {
+				Count     int    `json:"count"`
+				SizeBytes int    `json:"sizeBytes"`
+				File      string `json:"file"`
+			} `json:"fileReference"`
+		} `json:"image"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse image analysis: %w", err)
+	}
+
+	report := &InspectReport{
+		Size:        result.Image.SizeBytes,
+		WastedBytes: result.Image.InefficientBytes,
+		Efficiency:  result.Image.EfficiencyScore,
+		Layers:      make([]*LayerInfo, 0, len(result.Layer)),
+		WastedFiles: []*WastedFile{},
+	}
+	for _, l := range result.Layer {
+		report.Layers = append(report.Layers, &LayerInfo{
+			Index:   l.Index,
+			Digest:  l.DigestID,
+			Size:    l.SizeBytes,
+			Command: strings.TrimSpace(l.Command),
+		})
+	}
+
+	// Keep the ten files wasting the most space
+	files := result.Image.FileReference
+	sort.SliceStable(files, func(i, j int) bool { return files[i].SizeBytes > files[j].SizeBytes })
+	for i, f := range files {
+		if i == 10 {
+			break
+		}
+		report.WastedFiles = append(report.WastedFiles, &WastedFile{
+			Path:  f.File,
+			Count: f.Count,
+			Size:  f.SizeBytes,
+		})
+	}
+
+	return report, nil
+}
+
+// summary formats the report for budget failures: totals, layers and the
+// files wasting the most space
+func (r *InspectReport) summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Size: %s, wasted: %s, efficiency: %.1f%%\n", formatSize(r.Size), formatSize(r.WastedBytes), r.Efficiency*100)
+
+	b.WriteString("\nLayers:\n")
+	for _, l := range r.Layers {
+		command := strings.Join(strings.Fields(l.Command), " ")
+		if len(command) > 80 {
+			command = command[:77] + "..."
+		}
+		fmt.Fprintf(&b, "  %3d  %9s  %s\n", l.Index, formatSize(l.Size), command)
+	}
+
+	if len(r.WastedFiles) > 0 {
+		b.WriteString("\nWasted space:\n")
+		for _, f := range r.WastedFiles {
+			fmt.Fprintf(&b, "  %9s  %dx  %s\n", formatSize(f.Size), f.Count, f.Path)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// sizeUnits maps size suffixes to their multiplier
+var sizeUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+}
+
+// parseSize parses a human-readable size (e.g., "250MB", "1.5GiB") into bytes
+func parseSize(size string) (int, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+
+	value, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := sizeUnits[strings.TrimSpace(s[i:])]
+	if err != nil || !ok || value <= 0 {
+		return 0, fmt.Errorf("invalid size: %s (e.g., 250MB, 1.5GiB)", size)
+	}
+	return int(value * unit), nil
+}
+
+// formatSize formats a number of bytes for error messages
+func formatSize(bytes int) string {
+	switch {
+	case bytes >= 1e9:
+		return fmt.Sprintf("%.2fGB", float64(bytes)/1e9)
+	case bytes >= 1e6:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/1e6)
+	case bytes >= 1e3:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/1e3)
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
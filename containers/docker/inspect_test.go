@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInspectReportSummary(t *testing.T) {
+	report := &InspectReport{
+		Size:        310_000_000,
+		WastedBytes: 42_000_000,
+		Efficiency:  0.86,
+		Layers: []*LayerInfo{
+			{Index: 0, Size: 80_000_000, Command: "#(nop) ADD file:abc in /"},
+			{Index: 1, Size: 230_000_000, Command: "RUN apt-get update &&\n    apt-get install -y build-essential"},
+		},
+		WastedFiles: []*WastedFile{{Path: "/var/lib/apt/lists/archive", Count: 2, Size: 42_000_000}},
+	}
+
+	summary := report.summary()
+	for _, want := range []string{
+		"Size: 310.0MB, wasted: 42.0MB, efficiency: 86.0%",
+		"230.0MB  RUN apt-get update && apt-get install -y build-essential",
+		"42.0MB  2x  /var/lib/apt/lists/archive",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary() does not contain %q:\n%s", want, summary)
+		}
+	}
+}
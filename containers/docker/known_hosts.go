@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// pinnedKnownHosts returns a known_hosts file with the scanned host keys whose
+// SHA256 fingerprint (as printed by ssh-keygen -lf) matches the pinned one.
+// Fails if the host offered no matching key, so the connection is never made
+// to an unverified host.
+func pinnedKnownHosts(ctx context.Context, hostKeys *dagger.File, fingerprint string) (*dagger.File, error) {
+	keys, err := hostKeys.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read host keys: %w", err)
+	}
+
+	knownHosts, err := pinnedHostKeys(keys, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	return dag.Directory().WithNewFile("known_hosts", knownHosts).File("known_hosts"), nil
+}
+
+// pinnedHostKeys returns the known_hosts lines whose key matches a SHA256
+// fingerprint (checked by validateHostKeyOptions)
+func pinnedHostKeys(hostKeys, fingerprint string) (string, error) {
+	var matched []string
+	var hosts []string
+	for _, line := range strings.Split(hostKeys, "\n") {
+		// host key-type base64-key [comment]
+		fields := strings.Fields(line)
+		if len(fields) < 3 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+		if !slices.Contains(hosts, fields[0]) {
+			hosts = append(hosts, fields[0])
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(blob)
+		if "SHA256:"+base64.RawStdEncoding.EncodeToString(sum[:]) == fingerprint {
+			matched = append(matched, strings.Join(fields[:3], " "))
+		}
+	}
+
+	switch {
+	case len(hosts) == 0:
+		return "", fmt.Errorf("no host key received")
+	case len(matched) == 0:
+		return "", fmt.Errorf("no host key of %s matches %s", strings.Join(hosts, ", "), fingerprint)
+	}
+	return strings.Join(matched, "\n") + "\n", nil
+}
+
+// validateHostKeyOptions requires exactly one host key verification option
+func validateHostKeyOptions(knownHosts *dagger.File, fingerprint string, insecure bool) error {
+	options := 0
+	for _, set := range []bool{knownHosts != nil, fingerprint != "", insecure} {
+		if set {
+			options++
+		}
+	}
+	switch {
+	case options == 0:
+		return fmt.Errorf("host key verification required: provide knownHosts or hostKeyFingerprint (or insecureIgnoreHostKey to disable verification)")
+	case options > 1:
+		return fmt.Errorf("knownHosts, hostKeyFingerprint and insecureIgnoreHostKey are mutually exclusive")
+	case fingerprint != "" && !strings.HasPrefix(fingerprint, "SHA256:"):
+		return fmt.Errorf("invalid host key fingerprint %q: expected SHA256:... as printed by ssh-keygen -lf", fingerprint)
+	}
+	return nil
+}
+
+// sshEndpoint returns the host and port of an SSH Git URL
+// ("ssh://[user@]host[:port]/path" or "[user@]host:path")
+func sshEndpoint(url string) (string, int, error) {
+	if rest, ok := strings.CutPrefix(url, "ssh://"); ok {
+		authority, _, _ := strings.Cut(rest, "/")
+		if _, hostPort, found := strings.Cut(authority, "@"); found {
+			authority = hostPort
+		}
+		host, portValue, found := strings.Cut(authority, ":")
+		port := 22
+		if found {
+			n, err := strconv.Atoi(portValue)
+			if err != nil || n <= 0 || n > 65535 {
+				return "", 0, fmt.Errorf("invalid port in %s", url)
+			}
+			port = n
+		}
+		if host == "" {
+			return "", 0, fmt.Errorf("missing host in %s", url)
+		}
+		return host, port, nil
+	}
+
+	authority, _, found := strings.Cut(url, ":")
+	if !found || strings.Contains(authority, "/") || strings.Contains(url, "://") {
+		return "", 0, fmt.Errorf("not an SSH URL: %s", url)
+	}
+	if _, host, found := strings.Cut(authority, "@"); found {
+		authority = host
+	}
+	if authority == "" {
+		return "", 0, fmt.Errorf("missing host in %s", url)
+	}
+	return authority, 22, nil
+}
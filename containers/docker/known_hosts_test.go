@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+)
+
+const (
+	// testHostKey is an ed25519 public key and testHostKeyFingerprint its
+	// fingerprint as printed by ssh-keygen -lf
+	testHostKey            = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIFtUpn4OObr1HNC7u7J1/Umh/Cvz/zRHdKkJNfNf3zTf"
+	testHostKeyFingerprint = "SHA256:Dlnh/skgsuA0FHw6gwpZ9yFVjpDUYcFALkS/sv7rm9E"
+)
+
+func TestPinnedHostKeys(t *testing.T) {
+	otherKey := "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQC7"
+	tests := []struct {
+		name        string
+		hostKeys    string
+		fingerprint string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "matching key among others",
+			hostKeys:    "# host:22 SSH-2.0-OpenSSH_9.6\n[git.example.com]:2222 " + otherKey + "\n[git.example.com]:2222 " + testHostKey + "\n",
+			fingerprint: testHostKeyFingerprint,
+			want:        "[git.example.com]:2222 " + testHostKey + "\n",
+		},
+		{
+			name:        "no matching key",
+			hostKeys:    "git.example.com " + otherKey + "\n",
+			fingerprint: testHostKeyFingerprint,
+			wantErr:     true,
+		},
+		{
+			name:        "no key received",
+			hostKeys:    "",
+			fingerprint: testHostKeyFingerprint,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pinnedHostKeys(tt.hostKeys, tt.fingerprint)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("pinnedHostKeys() = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSSHEndpoint(t *testing.T) {
+	tests := []struct {
+		url      string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"git@github.com:org/app.git", "github.com", 22, false},
+		{"github.com:org/app.git", "github.com", 22, false},
+		{"ssh://git@git.example.com:2222/org/app.git", "git.example.com", 2222, false},
+		{"ssh://git.example.com/org/app.git", "git.example.com", 22, false},
+		{"ssh://git@git.example.com:ssh/org/app.git", "", 0, true},
+		{"https://github.com/org/app.git", "", 0, true},
+		{"/srv/git/app.git", "", 0, true},
+	}
+	for _, tt := range tests {
+		host, port, err := sshEndpoint(tt.url)
+		if (err != nil) != tt.wantErr || host != tt.wantHost || port != tt.wantPort {
+			t.Errorf("sshEndpoint(%q) = %q, %d, %v; want %q, %d, error %v", tt.url, host, port, err, tt.wantHost, tt.wantPort, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateHostKeyOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		fingerprint string
+		insecure    bool
+		wantErr     bool
+	}{
+		{"fingerprint", testHostKeyFingerprint, false, false},
+		{"insecure", "", true, false},
+		{"nothing", "", false, true},
+		{"both", testHostKeyFingerprint, true, true},
+		{"invalid fingerprint", "Dlnh/skgsuA0FHw6gwpZ9yFVjpDUYcFALkS", false, true},
+	}
+	for _, tt := range tests {
+		if err := validateHostKeyOptions(nil, tt.fingerprint, tt.insecure); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateHostKeyOptions() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
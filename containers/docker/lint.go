@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// hadolintImage lints Dockerfiles against best-practice rules
+const hadolintImage = "hadolint/hadolint:v2.12.0-alpine"
+
+// Lint checks a Dockerfile with hadolint before it is built
+//
+// Returns the findings in the requested format (text, json or sarif).
+// Fails when a finding at or above the failure threshold is reported, so it
+// can gate merge requests. A .hadolint.yaml file in source is honored.
+func (m *Docker) Lint(
+	ctx context.Context,
+	// Directory containing the Dockerfile
+	// +ignore=[".git", "**/.gitignore"]
+	source *dagger.Directory,
+	// Path to Dockerfile relative to source
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+	// Output format (text, json, sarif)
+	// +optional
+	// +default="text"
+	format string,
+	// Rules to ignore (e.g., "DL3008", "SC2086")
+	// +optional
+	ignore []string,
+	// Minimum severity that fails the lint (error, warning, info, style, none)
+	// +optional
+	// +default="error"
+	failureThreshold string,
+) (string, error) {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	outputFormat := format
+	switch format {
+	case "", "text":
+		outputFormat = "tty"
+	case "json", "sarif":
+	default:
+		return "", fmt.Errorf("unsupported lint format: %s (supported: text, json, sarif)", format)
+	}
+
+	if failureThreshold == "" {
+		failureThreshold = "error"
+	}
+	if !slices.Contains([]string{"error", "warning", "info", "style", "none"}, failureThreshold) {
+		return "", fmt.Errorf("invalid failure threshold: %s (supported: error, warning, info, style, none)", failureThreshold)
+	}
+
+	args := []string{
+		"hadolint",
+		"--no-color",
+		"--format", outputFormat,
+		"--failure-threshold", failureThreshold,
+	}
+	if failureThreshold == "none" {
+		args = append(args, "--no-fail")
+	}
+	for _, rule := range ignore {
+		args = append(args, "--ignore", rule)
+	}
+	args = append(args, dockerfile)
+
+	// Keep the report when the threshold is exceeded
+	container := dag.Container().
+		From(hadolintImage).
+		WithMountedDirectory("/src", source).
+		WithWorkdir("/src").
+		WithExec(args, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	output, err := container.Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("lint failed to run: %w", err)
+	}
+	stderr, err := container.Stderr(ctx)
+	if err != nil {
+		return "", fmt.Errorf("lint failed to run: %w", err)
+	}
+	exitCode, err := container.ExitCode(ctx)
+	if err != nil {
+		return "", fmt.Errorf("lint failed to run: %w", err)
+	}
+	if err := lintError(exitCode, output, stderr, failureThreshold, dockerfile); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
+// lintError tells lint findings from hadolint failures. hadolint exits with 1
+// for both, but reports findings on stdout and its own errors (missing
+// Dockerfile, invalid config) on stderr.
+func lintError(exitCode int, stdout, stderr, failureThreshold, dockerfile string) error {
+	switch {
+	case exitCode == 0:
+		return nil
+	case exitCode == 1 && strings.TrimSpace(stderr) == "" && strings.TrimSpace(stdout) != "":
+		return fmt.Errorf("lint failed: findings at or above %s in %s\n%s", failureThreshold, dockerfile, stdout)
+	}
+
+	details := strings.TrimSpace(stderr)
+	if details == "" {
+		details = strings.TrimSpace(stdout)
+	}
+	return fmt.Errorf("hadolint failed on %s with exit code %d\n%s", dockerfile, exitCode, details)
+}
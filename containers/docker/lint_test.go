@@ -0,0 +1,32 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLintError(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		stdout   string
+		stderr   string
+		want     string
+	}{
+		{"clean", 0, "", "", ""},
+		{"findings", 1, "Dockerfile:3 DL3008 warning: Pin versions in apt get install", "", "findings at or above error"},
+		{"missing dockerfile", 1, "", "hadolint: Dockerfile: withBinaryFile: does not exist (No such file or directory)", "hadolint failed on Dockerfile with exit code 1\nhadolint: Dockerfile: withBinaryFile"},
+		{"crash", 139, "", "", "hadolint failed on Dockerfile with exit code 139"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := lintError(tt.exitCode, tt.stdout, tt.stderr, "error", "Dockerfile")
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("lintError() = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("lintError() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -10,16 +10,58 @@ import (
 
 // Docker module for building and pushing container images to registries
 type Docker struct {
-	// Registry authentication
-	RegistryHost     string
-	RegistryUsername string
-	RegistryPassword *dagger.Secret
+	// Registry authentication, keyed by host
+	Registries []DockerRegistry
 
 	// Build configuration
-	BuildArgs []DockerBuildArg
-	Tags      []string
-	Target    string
-	Platform  dagger.Platform
+	BuildArgs    []DockerBuildArg
+	BuildSecrets []DockerBuildSecret
+	Tags         []string
+	Target       string
+	Platforms    []dagger.Platform
+
+	// Semantic version tag expansion policy (see WithTagPolicy())
+	TagChannels      []string
+	TagMoveLatest    bool
+	TagFloating      bool
+	TagBuildMetadata string
+
+	// Registry-backed build cache
+	CacheRef          string
+	CacheIgnoreErrors bool
+
+	// SBOM generation on Push (empty disables it)
+	SbomFormat string
+
+	// Provenance attestation on Push (empty builder ID disables it)
+	ProvenanceBuilder string
+
+	// Vulnerability scan gate for BuildAndPush (nil database disables it)
+	ScanDB        *dagger.Directory
+	ScanSeverity  string
+	ScanAllowlist *dagger.File
+
+	// Image signing with cosign (nil key disables it)
+	SigningKey      *dagger.Secret
+	SigningPassword *dagger.Secret
+	SigningTlog     bool
+
+	// Image metadata
+	Labels      []DockerKeyValue
+	Annotations []DockerKeyValue
+	GitRevision string
+	GitSource   string
+	// Commit time of GitRevision (RFC 3339), the image creation time
+	GitCommitTime string
+}
+
+// DockerRegistry represents credentials for a registry host
+type DockerRegistry struct {
+	Host     string
+	Username string
+	Password *dagger.Secret
+	// Only used to pull base images, never pushed to
+	PullOnly bool
 }
 
 // DockerBuildArg represents a Docker build argument
@@ -28,12 +70,34 @@ type DockerBuildArg struct {
 	Value string
 }
 
+// DockerBuildSecret represents a secret exposed to RUN --mount=type=secret
+type DockerBuildSecret struct {
+	ID     string
+	Secret *dagger.Secret
+}
+
+// DockerKeyValue represents an image label or manifest annotation
+type DockerKeyValue struct {
+	Key   string
+	Value string
+}
+
 // New creates a new Docker instance with default configuration
 func New() *Docker {
 	return &Docker{
-		BuildArgs: []DockerBuildArg{},
-		Tags:      []string{},
-		Platform:  "linux/amd64",
+		Registries:   []DockerRegistry{},
+		BuildArgs:    []DockerBuildArg{},
+		BuildSecrets: []DockerBuildSecret{},
+		Tags:         []string{},
+		Platforms:    []dagger.Platform{},
+		Labels:       []DockerKeyValue{},
+		Annotations:  []DockerKeyValue{},
+
+		// Default tag policy
+		TagChannels:      []string{"dev", "rc"},
+		TagMoveLatest:    true,
+		TagFloating:      true,
+		TagBuildMetadata: "strip",
 	}
 }
 
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
+)
+
+// regctlImage copies images between registries without a local daemon
+const regctlImage = "ghcr.io/regclient/regctl:v0.7.2-alpine"
+
+// Promote copies an existing image to the configured registry without rebuilding
+//
+// The source reference is resolved to a digest and copied registry-to-registry
+// with all platforms, OCI referrers (SBOMs, attestations) and cosign
+// signatures. The copy is published under every configured tag (see WithTag())
+// in every push registry.
+// Requires registry authentication configured via WithRegistry().
+func (m *Docker) Promote(
+	ctx context.Context,
+	// Source image reference (e.g., "devacr.azurecr.io/myapp:v1.2.3" or "...@sha256:...")
+	sourceRef string,
+	// Source registry username
+	// +optional
+	sourceUsername string,
+	// Source registry password or token
+	// +optional
+	sourcePassword *dagger.Secret,
+	// Target image name without registry prefix (defaults to the source repository path)
+	// +optional
+	imageName string,
+) ([]*PublishedImage, error) {
+	registries := m.pushRegistries()
+	if len(registries) == 0 {
+		return nil, fmt.Errorf("registry not configured: use WithRegistry() first")
+	}
+
+	sourceHost, sourceRepo := splitReference(sourceRef)
+	if imageName == "" {
+		imageName = sourceRepo
+	}
+	if err := validateImageName(imageName); err != nil {
+		return nil, fmt.Errorf("invalid image name: %w", err)
+	}
+
+	var extra []registryCredential
+	if sourceUsername != "" && sourcePassword != nil {
+		extra = append(extra, registryCredential{
+			host:     sourceHost,
+			username: sourceUsername,
+			password: sourcePassword,
+		})
+	}
+
+	// Registry operations must run on every call, never from Dagger's cache
+	regctl := m.withRegistryAuthWrapper(dag.Container().From(regctlImage), extra...).
+		WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String())
+
+	// Resolve the source to a digest so every tag points to the exact same image
+	output, err := regctl.
+		WithExec([]string{"with-registry-auth", "regctl", "image", "digest", sourceRef}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", sourceRef, err)
+	}
+	digest := strings.TrimSpace(output)
+	source := buildRepository(sourceHost, sourceRepo) + "@" + digest
+
+	tags := m.getDefaultTags()
+	published := make([]*PublishedImage, 0, len(tags)*len(registries))
+
+	for _, registry := range registries {
+		for i, tag := range tags {
+			target := buildFullReference(registry.Host, imageName, tag)
+
+			args := []string{"with-registry-auth", "regctl", "image", "copy"}
+			if i == 0 {
+				// Referrers and signature tags only need to be copied once per registry
+				args = append(args, "--referrers", "--digest-tags")
+			}
+			args = append(args, source, target)
+
+			if _, err := regctl.WithExec(args).Sync(ctx); err != nil {
+				return nil, publishError(target, published, err)
+			}
+			published = append(published, &PublishedImage{
+				Reference: target,
+				Digest:    digest,
+			})
+		}
+	}
+
+	return published, nil
+}
+
+// splitReference splits an image reference into registry host and repository path,
+// dropping any tag or digest (e.g., "myacr.azurecr.io/team/app:v1" -> "myacr.azurecr.io", "team/app").
+// References without a registry host resolve to Docker Hub.
+func splitReference(ref string) (string, string) {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+
+	host, repo, found := strings.Cut(ref, "/")
+	if !found || (!strings.ContainsAny(host, ".:") && host != "localhost") {
+		repo = ref
+		if !strings.Contains(repo, "/") {
+			repo = "library/" + repo
+		}
+		return "docker.io", repo
+	}
+	return host, repo
+}
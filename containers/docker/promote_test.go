@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitReference(t *testing.T) {
+	tests := []struct {
+		ref      string
+		wantHost string
+		wantRepo string
+	}{
+		{"myacr.azurecr.io/team/app:v1", "myacr.azurecr.io", "team/app"},
+		{"myacr.azurecr.io/app:v1@sha256:abc123", "myacr.azurecr.io", "app"},
+		{"localhost:5000/app", "localhost:5000", "app"},
+		{"nginx:1.27", "docker.io", "library/nginx"},
+		{"myorg/app", "docker.io", "myorg/app"},
+	}
+	for _, tt := range tests {
+		host, repo := splitReference(tt.ref)
+		if host != tt.wantHost || repo != tt.wantRepo {
+			t.Errorf("splitReference(%q) = %q, %q; want %q, %q", tt.ref, host, repo, tt.wantHost, tt.wantRepo)
+		}
+	}
+}
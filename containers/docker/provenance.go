@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
+)
+
+const (
+	// provenanceArtifactType is the OCI artifact type of in-toto attestations
+	provenanceArtifactType = "application/vnd.in-toto+json"
+	// provenanceBuildType identifies builds made with Build()
+	provenanceBuildType = "urn:dagger:docker:build:v1"
+	// defaultBuilderID identifies the builder when WithProvenance() is given none
+	defaultBuilderID = "urn:dagger:docker"
+)
+
+// provenanceStatement is an in-toto statement carrying a SLSA v1 provenance predicate
+// (see https://slsa.dev/spec/v1.0/provenance)
+type provenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []provenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     provenancePredicate `json:"predicate"`
+}
+
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type provenancePredicate struct {
+	BuildDefinition struct {
+		BuildType            string                 `json:"buildType"`
+		ExternalParameters   map[string]any         `json:"externalParameters"`
+		ResolvedDependencies []provenanceDependency `json:"resolvedDependencies,omitempty"`
+	} `json:"buildDefinition"`
+	RunDetails struct {
+		Builder struct {
+			ID string `json:"id"`
+		} `json:"builder"`
+		Metadata struct {
+			StartedOn  string `json:"startedOn,omitempty"`
+			FinishedOn string `json:"finishedOn,omitempty"`
+		} `json:"metadata"`
+	} `json:"runDetails"`
+}
+
+type provenanceDependency struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance generates a SLSA provenance attestation for a published image
+//
+// Returns an in-toto statement (SLSA v1 provenance predicate) describing how the
+// image was built with the current configuration: tags, target, platforms,
+// build arguments, build secret ids (never their values) and the Git source
+// and revision recorded by WithGitTags() or BuildFromGit().
+// Use WithProvenance() to attach it to every pushed digest.
+func (m *Docker) Provenance(
+	// Digest reference of the published image (e.g., "myacr.azurecr.io/myapp@sha256:...")
+	reference string,
+	// Builder identity (e.g., the CI workflow URL)
+	// +optional
+	// +default="urn:dagger:docker"
+	builderId string,
+) (*dagger.File, error) {
+	if builderId == "" {
+		builderId = defaultBuilderID
+	}
+
+	statement, err := m.provenanceStatement(reference, builderId, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return dag.Directory().WithNewFile("provenance.json", string(statement)).File("provenance.json"), nil
+}
+
+// provenanceStatement returns the provenance statement of a digest reference.
+// Zero times are omitted.
+func (m *Docker) provenanceStatement(reference, builderID string, startedOn, finishedOn time.Time) ([]byte, error) {
+	repository, digest, found := strings.Cut(digestReference(reference), "@")
+	algorithm, hex, valid := strings.Cut(digest, ":")
+	if !found || !valid || hex == "" {
+		return nil, fmt.Errorf("invalid digest reference %q: expected <repository>@sha256:<digest>", reference)
+	}
+
+	statement := provenanceStatement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       []provenanceSubject{{Name: repository, Digest: map[string]string{algorithm: hex}}},
+		PredicateType: "https://slsa.dev/provenance/v1",
+	}
+
+	buildArgs := map[string]string{}
+	for _, arg := range m.BuildArgs {
+		buildArgs[arg.Key] = arg.Value
+	}
+	secretIDs := []string{}
+	for _, s := range m.BuildSecrets {
+		secretIDs = append(secretIDs, s.ID)
+	}
+	parameters := map[string]any{
+		"tags":         m.getDefaultTags(),
+		"platforms":    m.getPlatforms(),
+		"buildArgs":    buildArgs,
+		"buildSecrets": secretIDs,
+	}
+	if m.Target != "" {
+		parameters["target"] = m.Target
+	}
+	if m.GitSource != "" {
+		parameters["source"] = m.GitSource
+	}
+
+	definition := &statement.Predicate.BuildDefinition
+	definition.BuildType = provenanceBuildType
+	definition.ExternalParameters = parameters
+	if m.GitSource != "" && m.GitRevision != "" {
+		definition.ResolvedDependencies = []provenanceDependency{{
+			URI:    "git+" + m.GitSource,
+			Digest: map[string]string{"gitCommit": m.GitRevision},
+		}}
+	}
+
+	run := &statement.Predicate.RunDetails
+	run.Builder.ID = builderID
+	if !startedOn.IsZero() {
+		run.Metadata.StartedOn = startedOn.UTC().Format(time.RFC3339)
+	}
+	if !finishedOn.IsZero() {
+		run.Metadata.FinishedOn = finishedOn.UTC().Format(time.RFC3339)
+	}
+
+	return json.MarshalIndent(statement, "", "  ")
+}
+
+// attachProvenance attaches the provenance statement of a published digest as
+// an OCI referrer
+func (m *Docker) attachProvenance(
+	ctx context.Context,
+	// Digest reference of the published image (e.g., "registry/app@sha256:...")
+	reference string,
+	startedOn time.Time,
+) error {
+	statement, err := m.provenanceStatement(reference, m.ProvenanceBuilder, startedOn, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = m.orasContainer().
+		WithWorkdir("/provenance").
+		WithNewFile("/provenance/provenance.json", string(statement)).
+		WithExec([]string{
+			"with-registry-auth", "oras", "attach",
+			"--artifact-type", provenanceArtifactType,
+			reference,
+			"provenance.json:" + provenanceArtifactType,
+		}).
+		Sync(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to attach provenance to %s: %w", reference, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvenanceStatement(t *testing.T) {
+	m := New()
+	m.Tags = []string{"v1.2.3"}
+	m.BuildArgs = []DockerBuildArg{{Key: "GO_VERSION", Value: "1.23"}}
+	m.BuildSecrets = []DockerBuildSecret{{ID: "npm_token"}}
+	m.GitSource = "https://github.com/org/app"
+	m.GitRevision = "0123456789abcdef0123456789abcdef01234567"
+
+	digest := strings.Repeat("ab", 32)
+	started := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	output, err := m.provenanceStatement("registry.example.com:5000/org/app:v1.2.3@sha256:"+digest, "https://ci.example.com/job", started, started.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statement provenanceStatement
+	if err := json.Unmarshal(output, &statement); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := statement.Subject; len(got) != 1 || got[0].Name != "registry.example.com:5000/org/app" || got[0].Digest["sha256"] != digest {
+		t.Errorf("subject = %+v", got)
+	}
+	if statement.PredicateType != "https://slsa.dev/provenance/v1" {
+		t.Errorf("predicateType = %q", statement.PredicateType)
+	}
+
+	definition := statement.Predicate.BuildDefinition
+	if definition.ExternalParameters["source"] != m.GitSource {
+		t.Errorf("source = %v", definition.ExternalParameters["source"])
+	}
+	if secrets, _ := json.Marshal(definition.ExternalParameters["buildSecrets"]); string(secrets) != `["npm_token"]` {
+		t.Errorf("buildSecrets = %s", secrets)
+	}
+	if deps := definition.ResolvedDependencies; len(deps) != 1 || deps[0].URI != "git+"+m.GitSource || deps[0].Digest["gitCommit"] != m.GitRevision {
+		t.Errorf("resolvedDependencies = %+v", deps)
+	}
+
+	run := statement.Predicate.RunDetails
+	if run.Builder.ID != "https://ci.example.com/job" || run.Metadata.StartedOn != "2026-10-01T12:00:00Z" || run.Metadata.FinishedOn != "2026-10-01T12:01:00Z" {
+		t.Errorf("runDetails = %+v", run)
+	}
+}
+
+func TestProvenanceStatementInvalidReference(t *testing.T) {
+	for _, reference := range []string{"registry.example.com/app:v1", "registry.example.com/app@sha256", "registry.example.com/app@"} {
+		if _, err := New().provenanceStatement(reference, defaultBuilderID, time.Time{}, time.Time{}); err == nil {
+			t.Errorf("provenanceStatement(%q) succeeded, want error", reference)
+		}
+	}
+}
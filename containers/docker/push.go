@@ -3,51 +3,257 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"dagger/docker/internal/dagger"
 )
 
+// PublishedImage is an image reference published to a registry
+type PublishedImage struct {
+	// Full tagged reference (e.g., "myregistry.azurecr.io/myapp:v1.0.0")
+	Reference string
+	// Manifest or index digest (e.g., "sha256:...")
+	Digest string
+}
+
 // Push pushes a built container to a registry
 //
 // Requires registry authentication configured via WithRegistry().
-// Pushes all configured tags (defaults to "latest" if none specified).
-// Returns the image digest.
+// Pushes all configured tags (defaults to "latest" if none specified) to
+// every registry not marked pull-only.
+// When platform variants are provided, they are published together with the
+// container as a single multi-platform image index.
+// When enabled with WithSbom(), an SBOM is attached to the pushed digest.
+// When enabled with WithProvenance(), a SLSA provenance attestation is attached
+// to the pushed digest.
+// When enabled with WithSigningKey(), every published digest is signed.
+// OCI labels and WithAnnotation() values are set as manifest annotations.
+// Returns the image digest (the index digest for multi-platform images).
+// Use Publish() to get every published reference.
 func (m *Docker) Push(
 	ctx context.Context,
 	// Built container from Build()
 	container *dagger.Container,
 	// Image name without registry prefix (e.g., "myapp" or "myorg/myapp")
 	imageName string,
+	// Additional platform variants from BuildPlatforms() to publish in the same image index
+	// +optional
+	platformVariants []*dagger.Container,
 ) (string, error) {
-	if m.RegistryHost == "" {
-		return "", fmt.Errorf("registry not configured: use WithRegistry() first")
+	published, err := m.Publish(ctx, container, imageName, platformVariants, false)
+	if err != nil {
+		return "", err
+	}
+
+	last := published[len(published)-1]
+	return last.Reference + "@" + last.Digest, nil
+}
+
+// Publish pushes a built container to a registry and reports every reference
+//
+// Same as Push(), but returns each published reference with its digest.
+// With singleUpload, the image is uploaded once under the first tag and the
+// remaining tags are added to the same manifest instead of re-publishing.
+// If a tag fails, the error lists the references already published.
+func (m *Docker) Publish(
+	ctx context.Context,
+	// Built container from Build()
+	container *dagger.Container,
+	// Image name without registry prefix (e.g., "myapp" or "myorg/myapp")
+	imageName string,
+	// Additional platform variants from BuildPlatforms() to publish in the same image index
+	// +optional
+	platformVariants []*dagger.Container,
+	// Upload once and tag the resulting digest instead of publishing each tag
+	// +optional
+	// +default=false
+	singleUpload bool,
+) ([]*PublishedImage, error) {
+	registries := m.pushRegistries()
+	if len(registries) == 0 {
+		return nil, fmt.Errorf("registry not configured: use WithRegistry() first")
 	}
-	if m.RegistryUsername == "" || m.RegistryPassword == nil {
-		return "", fmt.Errorf("registry credentials missing: use WithRegistry() with username and password")
+	for _, r := range registries {
+		if r.Username == "" || r.Password == nil {
+			return nil, fmt.Errorf("registry credentials missing for %s: use WithRegistry() with username and password", r.Host)
+		}
 	}
 	if err := validateImageName(imageName); err != nil {
-		return "", fmt.Errorf("invalid image name: %w", err)
+		return nil, fmt.Errorf("invalid image name: %w", err)
+	}
+
+	startedOn := time.Now()
+
+	// Authenticate to every registry
+	container = m.withRegistryAuth(container)
+
+	// Apply manifest annotations to every platform variant
+	annotations, err := m.manifestAnnotations(ctx, container)
+	if err != nil {
+		return nil, err
+	}
+	container = withAnnotations(container, annotations)
+	annotatedVariants := make([]*dagger.Container, 0, len(platformVariants))
+	for _, variant := range platformVariants {
+		annotatedVariants = append(annotatedVariants, withAnnotations(variant, annotations))
 	}
+	platformVariants = annotatedVariants
 
-	// Authenticate to registry
-	container = container.WithRegistryAuth(
-		m.RegistryHost,
-		m.RegistryUsername,
-		m.RegistryPassword,
-	)
+	publishOpts := dagger.ContainerPublishOpts{
+		PlatformVariants: platformVariants,
+	}
 
-	// Push all configured tags
+	// Push all configured tags to every registry
 	tags := m.getDefaultTags()
-	var lastDigest string
+	published := make([]*PublishedImage, 0, len(tags)*len(registries))
 
-	for _, tag := range tags {
-		fullReference := buildFullReference(m.RegistryHost, imageName, tag)
-		digest, err := container.Publish(ctx, fullReference)
+	// With singleUpload, only the first tag is uploaded and the others are added afterwards
+	uploadTags := tags
+	if singleUpload {
+		uploadTags = tags[:1]
+	}
+
+	for _, registry := range registries {
+		var uploadDigest string
+		for _, tag := range uploadTags {
+			fullReference := buildFullReference(registry.Host, imageName, tag)
+			ref, err := container.Publish(ctx, fullReference, publishOpts)
+			if err != nil {
+				return nil, publishError(fullReference, published, err)
+			}
+			_, uploadDigest, _ = strings.Cut(ref, "@")
+			published = append(published, &PublishedImage{
+				Reference: fullReference,
+				Digest:    uploadDigest,
+			})
+		}
+
+		if singleUpload && len(tags) > 1 {
+			tagged, err := m.tagDigest(ctx, registry.Host, imageName, uploadDigest, tags[1:])
+			if err != nil {
+				return nil, publishError(buildFullReference(registry.Host, imageName, tags[1]), published, err)
+			}
+			published = append(published, tagged...)
+		}
+	}
+
+	// Post-publish steps run once per distinct digest
+	var digests []string
+	for _, p := range published {
+		ref := digestReference(p.Reference + "@" + p.Digest)
+		if !slices.Contains(digests, ref) {
+			digests = append(digests, ref)
+		}
+	}
+
+	// Attach SBOMs to each published digest
+	if m.SbomFormat != "" {
+		variants := append([]*dagger.Container{container}, platformVariants...)
+		for _, ref := range digests {
+			if err := m.attachSboms(ctx, ref, variants); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Attach a provenance attestation to each published digest
+	if m.ProvenanceBuilder != "" {
+		for _, ref := range digests {
+			if err := m.attachProvenance(ctx, ref, startedOn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Sign each distinct digest once
+	if m.SigningKey != nil {
+		for _, ref := range digests {
+			if err := m.signImage(ctx, ref); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return published, nil
+}
+
+// manifestAnnotations returns the annotations to set on the image manifest:
+// the OCI standard labels of the built container followed by WithAnnotation() values
+func (m *Docker) manifestAnnotations(
+	ctx context.Context,
+	container *dagger.Container,
+) ([]DockerKeyValue, error) {
+	var annotations []DockerKeyValue
+
+	for _, key := range []string{
+		"org.opencontainers.image.created",
+		"org.opencontainers.image.title",
+		"org.opencontainers.image.version",
+		"org.opencontainers.image.revision",
+		"org.opencontainers.image.source",
+	} {
+		value, err := container.Label(ctx, key)
 		if err != nil {
-			return "", fmt.Errorf("failed to push %s: %w", fullReference, err)
+			return nil, fmt.Errorf("failed to read label %s: %w", key, err)
 		}
-		lastDigest = digest
+		if value != "" {
+			annotations = setKeyValue(annotations, key, value)
+		}
+	}
+
+	for _, a := range m.Annotations {
+		annotations = setKeyValue(annotations, a.Key, a.Value)
+	}
+
+	return annotations, nil
+}
+
+// withAnnotations sets manifest annotations on a container
+func withAnnotations(container *dagger.Container, annotations []DockerKeyValue) *dagger.Container {
+	for _, a := range annotations {
+		container = container.WithAnnotation(a.Key, a.Value)
+	}
+	return container
+}
+
+// tagDigest adds tags to an already published manifest without re-uploading it
+func (m *Docker) tagDigest(
+	ctx context.Context,
+	registryHost string,
+	imageName string,
+	digest string,
+	tags []string,
+) ([]*PublishedImage, error) {
+	repository := buildRepository(registryHost, imageName)
+
+	args := append([]string{"with-registry-auth", "oras", "tag", repository + "@" + digest}, tags...)
+	_, err := m.orasContainer().WithExec(args).Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged := make([]*PublishedImage, 0, len(tags))
+	for _, tag := range tags {
+		tagged = append(tagged, &PublishedImage{
+			Reference: buildFullReference(registryHost, imageName, tag),
+			Digest:    digest,
+		})
+	}
+	return tagged, nil
+}
+
+// publishError reports a failed reference together with those already published
+func publishError(reference string, published []*PublishedImage, err error) error {
+	if len(published) == 0 {
+		return fmt.Errorf("failed to push %s: %w", reference, err)
 	}
 
-	return lastDigest, nil
+	done := make([]string, 0, len(published))
+	for _, p := range published {
+		done = append(done, p.Reference)
+	}
+	return fmt.Errorf("failed to push %s (already published: %s): %w",
+		reference, strings.Join(done, ", "), err)
 }
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"dagger/docker/internal/dagger"
+)
+
+const (
+	// acrUsername is the fixed username used with ACR refresh tokens
+	acrUsername = "00000000-0000-0000-0000-000000000000"
+	// ecrUsername is the fixed username used with ECR authorization tokens
+	ecrUsername = "AWS"
+	// gcrUsername is the fixed username used with Google OAuth access tokens
+	gcrUsername = "oauth2accesstoken"
+)
+
+// ecrHostPattern extracts the region from an ECR registry host
+var ecrHostPattern = regexp.MustCompile(`^\d+\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com`)
+
+// AcrToken exchanges an Azure AD identity for an ACR refresh token
+//
+// Authenticates a service principal with a client secret, or with a federated
+// token (workload identity, GitHub/GitLab OIDC), then exchanges the Azure AD
+// access token for a registry refresh token.
+// Use the token as password with username 00000000-0000-0000-0000-000000000000.
+func (m *Docker) AcrToken(
+	ctx context.Context,
+	// ACR hostname (e.g., "myregistry.azurecr.io"); an http(s):// prefix overrides the exchange endpoint
+	registry string,
+	// Azure AD tenant ID
+	tenantId string,
+	// Service principal or managed identity client ID
+	clientId string,
+	// Service principal client secret
+	// +optional
+	clientSecret *dagger.Secret,
+	// Federated token used as client assertion instead of a client secret
+	// +optional
+	federatedToken *dagger.Secret,
+	// Azure AD authority host
+	// +optional
+	// +default="https://login.microsoftonline.com"
+	authorityHost string,
+) (*dagger.Secret, error) {
+	secret, err := readSecret(ctx, clientSecret, "client secret")
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := readSecret(ctx, federatedToken, "federated token")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := acrRefreshToken(ctx, registry, tenantId, clientId, secret, assertion, authorityHost)
+	if err != nil {
+		return nil, err
+	}
+	return dag.SetSecret("acr-token-"+registryHostname(registry), token), nil
+}
+
+// EcrPassword exchanges AWS credentials for an ECR registry password
+//
+// Equivalent to "aws ecr get-login-password": calls the ECR
+// GetAuthorizationToken API with the given access key.
+// Use the password with username AWS.
+func (m *Docker) EcrPassword(
+	ctx context.Context,
+	// AWS region of the registry (e.g., "eu-west-1")
+	region string,
+	// AWS access key ID
+	accessKeyId *dagger.Secret,
+	// AWS secret access key
+	secretAccessKey *dagger.Secret,
+	// AWS session token for temporary credentials
+	// +optional
+	sessionToken *dagger.Secret,
+	// ECR API endpoint (defaults to https://api.ecr.<region>.amazonaws.com)
+	// +optional
+	endpoint string,
+) (*dagger.Secret, error) {
+	keyID, err := readSecret(ctx, accessKeyId, "AWS access key ID")
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := readSecret(ctx, secretAccessKey, "AWS secret access key")
+	if err != nil {
+		return nil, err
+	}
+	token, err := readSecret(ctx, sessionToken, "AWS session token")
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := ecrPassword(ctx, region, keyID, secretKey, token, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return dag.SetSecret("ecr-password-"+region, password), nil
+}
+
+// GcrToken exchanges a Google service account key for an OAuth access token
+//
+// Works with Google Container Registry and Artifact Registry.
+// Use the token as password with username oauth2accesstoken.
+func (m *Docker) GcrToken(
+	ctx context.Context,
+	// Service account key (JSON)
+	serviceAccountKey *dagger.Secret,
+	// OAuth token endpoint (defaults to the token_uri of the key)
+	// +optional
+	tokenEndpoint string,
+) (*dagger.Secret, error) {
+	key, err := readSecret(ctx, serviceAccountKey, "service account key")
+	if err != nil {
+		return nil, err
+	}
+
+	token, email, err := gcrAccessToken(ctx, key, tokenEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return dag.SetSecret("gcr-token-"+email, token), nil
+}
+
+// acrRefreshToken performs the Azure AD and ACR token exchanges
+func acrRefreshToken(
+	ctx context.Context,
+	registry, tenantID, clientID string,
+	clientSecret, federatedToken string,
+	authorityHost string,
+) (string, error) {
+	if authorityHost == "" {
+		authorityHost = "https://login.microsoftonline.com"
+	}
+
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {clientID},
+		"scope":      {"https://management.azure.com/.default"},
+	}
+	switch {
+	case federatedToken != "":
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(federatedToken))
+	case clientSecret != "":
+		form.Set("client_secret", clientSecret)
+	default:
+		return "", fmt.Errorf("ACR authentication requires a client secret or a federated token")
+	}
+
+	var aad struct {
+		AccessToken string `json:"access_token"`
+	}
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authorityHost, "/"), tenantID)
+	if err := postForm(ctx, tokenURL, form, &aad); err != nil {
+		return "", fmt.Errorf("Azure AD authentication failed: %w", err)
+	}
+
+	var acr struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	exchange := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {registryHostname(registry)},
+		"tenant":       {tenantID},
+		"access_token": {aad.AccessToken},
+	}
+	if err := postForm(ctx, registryURL(registry)+"/oauth2/exchange", exchange, &acr); err != nil {
+		return "", fmt.Errorf("ACR token exchange failed: %w", err)
+	}
+	if acr.RefreshToken == "" {
+		return "", fmt.Errorf("ACR token exchange returned no refresh token")
+	}
+
+	return acr.RefreshToken, nil
+}
+
+// ecrPassword calls the ECR GetAuthorizationToken API with a SigV4-signed request
+func ecrPassword(
+	ctx context.Context,
+	region string,
+	accessKeyID, secretAccessKey, sessionToken string,
+	endpoint string,
+) (string, error) {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://api.ecr.%s.amazonaws.com", region)
+	}
+
+	body := []byte("{}")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+	signV4(req, body, strings.TrimSpace(accessKeyID), strings.TrimSpace(secretAccessKey), strings.TrimSpace(sessionToken), region, "ecr", time.Now().UTC())
+
+	var result struct {
+		AuthorizationData []struct {
+			AuthorizationToken string `json:"authorizationToken"`
+		} `json:"authorizationData"`
+	}
+	if err := doJSON(req, &result); err != nil {
+		return "", fmt.Errorf("ECR GetAuthorizationToken failed: %w", err)
+	}
+	if len(result.AuthorizationData) == 0 {
+		return "", fmt.Errorf("ECR returned no authorization data")
+	}
+
+	// Token is base64("AWS:<password>")
+	decoded, err := base64.StdEncoding.DecodeString(result.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		return "", fmt.Errorf("invalid ECR authorization token: %w", err)
+	}
+	_, password, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return "", fmt.Errorf("invalid ECR authorization token format")
+	}
+
+	return password, nil
+}
+
+// gcrAccessToken performs the OAuth 2.0 JWT bearer grant for a service account key.
+// Returns the access token and the service account email.
+func gcrAccessToken(
+	ctx context.Context,
+	serviceAccountKey string,
+	tokenEndpoint string,
+) (string, string, error) {
+	var key struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal([]byte(serviceAccountKey), &key); err != nil {
+		return "", "", fmt.Errorf("invalid service account key: %w", err)
+	}
+	if tokenEndpoint == "" {
+		tokenEndpoint = key.TokenURI
+	}
+	if tokenEndpoint == "" {
+		tokenEndpoint = "https://oauth2.googleapis.com/token"
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return "", "", fmt.Errorf("invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid service account private key: %w", err)
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", "", fmt.Errorf("service account private key is not an RSA key")
+	}
+
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iss":   key.ClientEmail,
+		"scope": "https://www.googleapis.com/auth/cloud-platform",
+		"aud":   tokenEndpoint,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign token request: %w", err)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+	if err := postForm(ctx, tokenEndpoint, form, &result); err != nil {
+		return "", "", fmt.Errorf("Google OAuth token exchange failed: %w", err)
+	}
+
+	return result.AccessToken, key.ClientEmail, nil
+}
+
+// signV4 signs an AWS API request with Signature Version 4
+func signV4(req *http.Request, body []byte, accessKeyID, secretAccessKey, sessionToken, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headerNames := []string{"content-type", "host", "x-amz-date"}
+	if sessionToken != "" {
+		headerNames = append(headerNames, "x-amz-security-token")
+	}
+	headerNames = append(headerNames, "x-amz-target")
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 computes an HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// postForm posts a URL-encoded form and decodes the JSON response
+func postForm(ctx context.Context, endpoint string, form url.Values, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doJSON(req, result)
+}
+
+// doJSON sends a request and decodes the JSON response, failing on non-2xx status
+func doJSON(req *http.Request, result any) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, result)
+}
+
+// readSecret returns the plaintext of an optional secret, empty when unset
+func readSecret(ctx context.Context, secret *dagger.Secret, name string) (string, error) {
+	if secret == nil {
+		return "", nil
+	}
+	plaintext, err := secret.Plaintext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return plaintext, nil
+}
+
+// registryHostname strips an optional URL scheme from a registry
+func registryHostname(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	return strings.TrimPrefix(registry, "http://")
+}
+
+// registryURL returns the base URL of a registry, defaulting to HTTPS
+func registryURL(registry string) string {
+	if strings.HasPrefix(registry, "http://") || strings.HasPrefix(registry, "https://") {
+		return strings.TrimSuffix(registry, "/")
+	}
+	return "https://" + registry
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	tests := []struct {
+		name          string
+		sessionToken  string
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "access key",
+			signedHeaders: "content-type;host;x-amz-date;x-amz-target",
+			signature:     "527a2155e9afc2426cb4c659023df9c031e8103458f349819615ff25111f5de0",
+		},
+		{
+			name:          "temporary credentials",
+			sessionToken:  "session-token",
+			signedHeaders: "content-type;host;x-amz-date;x-amz-security-token;x-amz-target",
+			signature:     "8102772839ac0eddeaadc71c776a7ea98f9507dcefa6da58d36088a9d49c6943",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte("{}")
+			req, err := http.NewRequest(http.MethodPost, "https://api.ecr.us-east-1.amazonaws.com/", strings.NewReader(string(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+			req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+			now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+			signV4(req, body, "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", tt.sessionToken, "us-east-1", "ecr", now)
+
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/ecr/aws4_request, " +
+				"SignedHeaders=" + tt.signedHeaders + ", Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != want {
+				t.Errorf("Authorization = %q, want %q", got, want)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %q", got)
+			}
+			if got := req.Header.Get("X-Amz-Security-Token"); got != tt.sessionToken {
+				t.Errorf("X-Amz-Security-Token = %q, want %q", got, tt.sessionToken)
+			}
+		})
+	}
+}
+
+func TestAcrRefreshToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/token":
+			if r.Form.Get("client_id") != "client" ||
+				(r.Form.Get("client_secret") != "secret" && r.Form.Get("client_assertion") != "federated") {
+				http.Error(w, "invalid client", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token":"aad-token"}`))
+		case "/oauth2/exchange":
+			if r.Form.Get("access_token") != "aad-token" || r.Form.Get("tenant") != "tenant" {
+				http.Error(w, "invalid access token", http.StatusUnauthorized)
+				return
+			}
+			// The service is the registry host, without the scheme of the mock endpoint
+			if r.Form.Get("service") != r.Host {
+				http.Error(w, "invalid service", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"refresh_token":"acr-refresh-token"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		clientSecret   string
+		federatedToken string
+		want           string
+		wantErr        bool
+	}{
+		{name: "client secret", clientSecret: "secret", want: "acr-refresh-token"},
+		{name: "federated token", federatedToken: "federated\n", want: "acr-refresh-token"},
+		{name: "wrong secret", clientSecret: "wrong", wantErr: true},
+		{name: "no credentials", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := acrRefreshToken(context.Background(), server.URL, "tenant", "client", tt.clientSecret, tt.federatedToken, server.URL)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("acrRefreshToken() = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEcrPassword(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/ecr/aws4_request") {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("X-Amz-Target") != "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken" {
+			http.Error(w, "unknown operation", http.StatusBadRequest)
+			return
+		}
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:ecr-password"))
+		w.Write([]byte(`{"authorizationData":[{"authorizationToken":"` + token + `"}]}`))
+	}))
+	defer server.Close()
+
+	got, err := ecrPassword(context.Background(), "eu-west-1", "AKIDEXAMPLE", "secret", "", server.URL)
+	if err != nil || got != "ecr-password" {
+		t.Errorf("ecrPassword() = %q, %v; want %q", got, err, "ecr-password")
+	}
+
+	if _, err := ecrPassword(context.Background(), "eu-west-1", "OTHERKEY", "secret", "", server.URL); err == nil {
+		t.Error("ecrPassword() with rejected credentials succeeded")
+	}
+}
+
+func TestGcrAccessToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			http.Error(w, "invalid grant", http.StatusBadRequest)
+			return
+		}
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, "invalid assertion", http.StatusBadRequest)
+			return
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err != nil || rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token":"gcr-access-token"}`))
+	}))
+	defer server.Close()
+
+	serviceAccountKey, err := json.Marshal(map[string]string{
+		"client_email": "builder@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, email, err := gcrAccessToken(context.Background(), string(serviceAccountKey), "")
+	if err != nil || token != "gcr-access-token" || email != "builder@project.iam.gserviceaccount.com" {
+		t.Errorf("gcrAccessToken() = %q, %q, %v", token, email, err)
+	}
+
+	if _, _, err := gcrAccessToken(context.Background(), `{"client_email":"x","private_key":"invalid"}`, server.URL); err == nil {
+		t.Error("gcrAccessToken() with an invalid key succeeded")
+	}
+}
+
+func TestRegistryHostname(t *testing.T) {
+	tests := []struct {
+		registry string
+		want     string
+	}{
+		{"myregistry.azurecr.io", "myregistry.azurecr.io"},
+		{"https://myregistry.azurecr.io", "myregistry.azurecr.io"},
+		{"http://127.0.0.1:5000", "127.0.0.1:5000"},
+	}
+	for _, tt := range tests {
+		if got := registryHostname(tt.registry); got != tt.want {
+			t.Errorf("registryHostname(%q) = %q, want %q", tt.registry, got, tt.want)
+		}
+	}
+}
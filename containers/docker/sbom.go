@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// syftImage generates SBOMs from image tarballs
+const syftImage = "anchore/syft:v1.18.1"
+
+// sbomArtifactTypes maps supported SBOM formats to their OCI artifact type
+var sbomArtifactTypes = map[string]string{
+	"spdx-json":      "application/spdx+json",
+	"cyclonedx-json": "application/vnd.cyclonedx+json",
+}
+
+// Sbom generates a Software Bill of Materials for a built container
+//
+// Uses syft to catalog the packages in the image.
+// Returns the SBOM document as a file.
+func (m *Docker) Sbom(
+	// Built container from Build()
+	container *dagger.Container,
+	// SBOM format ("spdx-json" or "cyclonedx-json")
+	// +optional
+	// +default="spdx-json"
+	format string,
+) (*dagger.File, error) {
+	if format == "" {
+		format = "spdx-json"
+	}
+	if _, ok := sbomArtifactTypes[format]; !ok {
+		return nil, fmt.Errorf("unsupported SBOM format: %s (supported: spdx-json, cyclonedx-json)", format)
+	}
+
+	return generateSbom(container, format), nil
+}
+
+// generateSbom runs syft against the container image tarball
+func generateSbom(container *dagger.Container, format string) *dagger.File {
+	return dag.Container().
+		From(syftImage).
+		WithMountedFile("/image.tar", container.AsTarball()).
+		WithExec([]string{
+			"/syft", "scan", "oci-archive:/image.tar",
+			"--output", format + "=/tmp/sbom.json",
+		}).
+		File("/tmp/sbom.json")
+}
+
+// attachSboms generates an SBOM for each platform variant and attaches it
+// to the published digest as an OCI referrer
+func (m *Docker) attachSboms(
+	ctx context.Context,
+	// Digest reference of the published image (e.g., "registry/app@sha256:...")
+	reference string,
+	variants []*dagger.Container,
+) error {
+	artifactType := sbomArtifactTypes[m.SbomFormat]
+
+	// ORAS pushes the SBOM as an artifact referring to the image digest
+	oras := m.orasContainer().WithWorkdir("/sbom")
+
+	for _, variant := range variants {
+		platform, err := variant.Platform(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read platform: %w", err)
+		}
+
+		// File name becomes the artifact title, so it identifies the platform
+		fileName := fmt.Sprintf("sbom-%s.%s.json",
+			strings.ReplaceAll(string(platform), "/", "-"),
+			strings.TrimSuffix(m.SbomFormat, "-json"))
+
+		_, err = oras.
+			WithFile("/sbom/"+fileName, generateSbom(variant, m.SbomFormat)).
+			WithExec([]string{
+				"with-registry-auth", "oras", "attach",
+				"--artifact-type", artifactType,
+				reference,
+				fileName + ":" + artifactType,
+			}).
+			Sync(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to attach SBOM for %s: %w", platform, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// trivyImage scans image tarballs for known vulnerabilities
+const trivyImage = "aquasec/trivy:0.57.1"
+
+// severityLevels orders Trivy severities from lowest to highest
+var severityLevels = map[string]int{
+	"UNKNOWN":  0,
+	"LOW":      1,
+	"MEDIUM":   2,
+	"HIGH":     3,
+	"CRITICAL": 4,
+}
+
+// ScanReport is the result of a vulnerability scan
+type ScanReport struct {
+	// Minimum severity that fails the scan
+	Threshold string
+	// True when no finding at or above the threshold remains after the allowlist
+	Passed bool
+	// Number of findings at or above the threshold, excluding allowlisted ones
+	Blocking int
+	// Number of findings ignored through the allowlist
+	Allowlisted int
+	// All findings reported by the scanner
+	Findings []*ScanFinding
+}
+
+// ScanFinding is a single vulnerability reported by the scanner
+type ScanFinding struct {
+	ID               string
+	Severity         string
+	Package          string
+	InstalledVersion string
+	FixedVersion     string
+	Title            string
+	Target           string
+	Allowlisted      bool
+}
+
+// Scan scans a built container for known vulnerabilities
+//
+// Runs Trivy offline against the container image using the supplied database,
+// so no network access to vulnerability feeds is required.
+// Returns a structured report; the report does not pass when findings at or
+// above the severity threshold are not covered by the allowlist.
+func (m *Docker) Scan(
+	ctx context.Context,
+	// Built container from Build()
+	container *dagger.Container,
+	// Trivy cache directory containing an offline vulnerability database (db/trivy.db)
+	db *dagger.Directory,
+	// Minimum severity that fails the scan (UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL)
+	// +optional
+	// +default="HIGH"
+	severity string,
+	// File listing accepted vulnerability IDs, one per line ("#" starts a comment)
+	// +optional
+	allowlist *dagger.File,
+) (*ScanReport, error) {
+	threshold, err := normalizeSeverity(severity)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := map[string]bool{}
+	if allowlist != nil {
+		contents, err := allowlist.Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read allowlist: %w", err)
+		}
+		accepted = parseAllowlist(contents)
+	}
+
+	output, err := dag.Container().
+		From(trivyImage).
+		WithMountedDirectory("/trivy-cache", db).
+		WithMountedFile("/image.tar", container.AsTarball()).
+		WithExec([]string{
+			"trivy", "image",
+			"--input", "/image.tar",
+			"--cache-dir", "/trivy-cache",
+			"--skip-db-update",
+			"--skip-java-db-update",
+			"--offline-scan",
+			"--scanners", "vuln",
+			"--format", "json",
+			"--quiet",
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("vulnerability scan failed: %w", err)
+	}
+
+	var result struct {
+		Results []struct {
+			Target          string
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+				Title            string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse scan report: %w", err)
+	}
+
+	report := &ScanReport{
+		Threshold: threshold,
+		Findings:  []*ScanFinding{},
+	}
+	for _, r := range result.Results {
+		for _, v := range r.Vulnerabilities {
+			finding := &ScanFinding{
+				ID:               v.VulnerabilityID,
+				Severity:         strings.ToUpper(v.Severity),
+				Package:          v.PkgName,
+				InstalledVersion: v.InstalledVersion,
+				FixedVersion:     v.FixedVersion,
+				Title:            v.Title,
+				Target:           r.Target,
+				Allowlisted:      accepted[v.VulnerabilityID],
+			}
+			report.Findings = append(report.Findings, finding)
+
+			switch {
+			case finding.Allowlisted:
+				report.Allowlisted++
+			case severityLevels[finding.Severity] >= severityLevels[threshold]:
+				report.Blocking++
+			}
+		}
+	}
+	report.Passed = report.Blocking == 0
+
+	return report, nil
+}
+
+// scanGate scans every platform variant and fails if any has blocking findings
+func (m *Docker) scanGate(ctx context.Context, variants []*dagger.Container) error {
+	for _, variant := range variants {
+		platform, err := variant.Platform(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read platform: %w", err)
+		}
+
+		report, err := m.Scan(ctx, variant, m.ScanDB, m.ScanSeverity, m.ScanAllowlist)
+		if err != nil {
+			return err
+		}
+		if report.Passed {
+			continue
+		}
+
+		var blocking []string
+		for _, f := range report.Findings {
+			if !f.Allowlisted && severityLevels[f.Severity] >= severityLevels[report.Threshold] {
+				blocking = append(blocking, fmt.Sprintf("  %s (%s) in %s %s", f.ID, f.Severity, f.Package, f.InstalledVersion))
+			}
+		}
+		return fmt.Errorf("vulnerability scan failed for %s: %d finding(s) at or above %s\n%s",
+			platform, report.Blocking, report.Threshold, strings.Join(blocking, "\n"))
+	}
+
+	return nil
+}
+
+// normalizeSeverity validates a severity threshold, defaulting to HIGH
+func normalizeSeverity(severity string) (string, error) {
+	if severity == "" {
+		return "HIGH", nil
+	}
+	severity = strings.ToUpper(severity)
+	if _, ok := severityLevels[severity]; !ok {
+		return "", fmt.Errorf("invalid severity: %s (supported: UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL)", severity)
+	}
+	return severity, nil
+}
+
+// parseAllowlist returns the vulnerability IDs listed in an allowlist file
+func parseAllowlist(contents string) map[string]bool {
+	ids := map[string]bool{}
+	for _, line := range strings.Split(contents, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		line = strings.TrimSpace(line)
+		if line != "" {
+			ids[line] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	contents := `# accepted risks, reviewed 2026-09
+CVE-2024-0001
+GHSA-xxxx-yyyy-zzzz  # no fix available
+
+`
+	want := map[string]bool{"CVE-2024-0001": true, "GHSA-xxxx-yyyy-zzzz": true}
+	if got := parseAllowlist(contents); !maps.Equal(got, want) {
+		t.Errorf("parseAllowlist() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	for _, severity := range []string{"unknown", "LOW", "Medium", "high", "CRITICAL"} {
+		if _, err := normalizeSeverity(severity); err != nil {
+			t.Errorf("normalizeSeverity(%q) error = %v", severity, err)
+		}
+	}
+	if got, _ := normalizeSeverity(""); got != "HIGH" {
+		t.Errorf("normalizeSeverity(\"\") = %q, want HIGH", got)
+	}
+	if _, err := normalizeSeverity("SEVERE"); err == nil || !strings.Contains(err.Error(), "UNKNOWN") {
+		t.Errorf("normalizeSeverity(\"SEVERE\") error = %v, want the accepted values", err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"dagger/docker/internal/dagger"
+)
+
+// cosignContainer creates a container with cosign and registry authentication
+func (m *Docker) cosignContainer() *dagger.Container {
+	return m.withRegistryAuthWrapper(
+		dag.Container().
+			From("alpine:3.20").
+			WithExec([]string{"apk", "add", "--no-cache", "cosign"}),
+	)
+}
+
+// signImage signs a digest reference with the configured cosign key
+func (m *Docker) signImage(
+	ctx context.Context,
+	// Digest reference (e.g., "registry/app@sha256:...")
+	reference string,
+) error {
+	container := m.cosignContainer().
+		WithSecretVariable("COSIGN_PRIVATE_KEY", m.SigningKey)
+
+	if m.SigningPassword != nil {
+		container = container.WithSecretVariable("COSIGN_PASSWORD", m.SigningPassword)
+	} else {
+		container = container.WithEnvVariable("COSIGN_PASSWORD", "")
+	}
+
+	args := []string{
+		"with-registry-auth", "cosign", "sign",
+		"--key", "env://COSIGN_PRIVATE_KEY",
+		"--yes",
+		fmt.Sprintf("--tlog-upload=%t", m.SigningTlog),
+		reference,
+	}
+
+	if _, err := container.WithExec(args).Sync(ctx); err != nil {
+		return fmt.Errorf("failed to sign %s: %w", reference, err)
+	}
+
+	return nil
+}
+
+// Verify checks the cosign signature of an image against a public key
+//
+// Uses the registry credentials configured via WithRegistry() to read
+// private images. Returns the verified signature payloads.
+func (m *Docker) Verify(
+	ctx context.Context,
+	// Image reference to verify (e.g., "myregistry.azurecr.io/myapp:v1.0.0")
+	reference string,
+	// Cosign public key
+	publicKey *dagger.File,
+	// Require the signature to be recorded in the Rekor transparency log
+	// +optional
+	// +default=false
+	transparencyLog bool,
+) (string, error) {
+	args := []string{
+		"with-registry-auth", "cosign", "verify",
+		"--key", "/cosign.pub",
+	}
+	if !transparencyLog {
+		args = append(args, "--insecure-ignore-tlog=true")
+	}
+	args = append(args, reference)
+
+	output, err := m.cosignContainer().
+		WithMountedFile("/cosign.pub", publicKey).
+		WithExec(args).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("signature verification failed for %s: %w", reference, err)
+	}
+
+	return output, nil
+}
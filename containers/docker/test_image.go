@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"dagger/docker/internal/dagger"
+)
+
+// TestReport is the result of TestImage()
+type TestReport struct {
+	// True when every check passed
+	Passed bool
+	// Number of checks run
+	Total int
+	// Number of failed checks
+	Failed int
+	// Result of each check
+	Results []*TestResult
+}
+
+// TestResult is the result of a single structure test check
+type TestResult struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+// structureTestSpec is the YAML spec accepted by TestImage()
+type structureTestSpec struct {
+	Files []struct {
+		Path string `json:"path"`
+		// Defaults to true when omitted
+		ShouldExist *bool `json:"shouldExist"`
+		// Regular expression the file content must match
+		Contains string `json:"contains"`
+	} `json:"files"`
+	Commands []struct {
+		Name    string   `json:"name"`
+		Command []string `json:"command"`
+		// Expected exit code (default 0)
+		ExitCode int `json:"exitCode"`
+		// Regular expressions stdout must match
+		ExpectedOutput []string `json:"expectedOutput"`
+		// Regular expressions stdout must not match
+		ExcludedOutput []string `json:"excludedOutput"`
+	} `json:"commands"`
+	Metadata struct {
+		Entrypoint   []string          `json:"entrypoint"`
+		User         *string           `json:"user"`
+		Workdir      *string           `json:"workdir"`
+		ExposedPorts []string          `json:"exposedPorts"`
+		Labels       map[string]string `json:"labels"`
+		Env          map[string]string `json:"env"`
+	} `json:"metadata"`
+}
+
+// TestImage runs container structure tests against a built image
+//
+// The YAML spec lists files that must (or must not) exist, commands that must
+// succeed with the expected output, and expected image metadata:
+//
+//	files:
+//	  - path: /app/server
+//	  - path: /root/.npmrc
+//	    shouldExist: false
+//	commands:
+//	  - name: version
+//	    command: ["/app/server", "--version"]
+//	    expectedOutput: ["v\\d+\\.\\d+"]
+//	metadata:
+//	  entrypoint: ["/app/server"]
+//	  user: "1000"
+//	  exposedPorts: ["8080/tcp"]
+//	  labels:
+//	    org.opencontainers.image.title: myapp
+//
+// Returns a pass/fail report with one result per check.
+func (m *Docker) TestImage(
+	ctx context.Context,
+	// Built container from Build()
+	container *dagger.Container,
+	// YAML file describing the checks
+	spec *dagger.File,
+) (*TestReport, error) {
+	specJSON, err := yamlToJSON(ctx, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test spec: %w", err)
+	}
+
+	var s structureTestSpec
+	if err := json.Unmarshal([]byte(specJSON), &s); err != nil {
+		return nil, fmt.Errorf("invalid test spec: %w", err)
+	}
+
+	report := &TestReport{Results: []*TestResult{}}
+	add := func(name string, passed bool, message string) {
+		report.Results = append(report.Results, &TestResult{Name: name, Passed: passed, Message: message})
+	}
+
+	// File checks
+	for _, f := range s.Files {
+		name := "file " + f.Path
+		shouldExist := f.ShouldExist == nil || *f.ShouldExist
+
+		exists, err := container.Exists(ctx, f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", f.Path, err)
+		}
+		if exists != shouldExist {
+			add(name, false, fmt.Sprintf("exists=%t, expected %t", exists, shouldExist))
+			continue
+		}
+
+		if exists && f.Contains != "" {
+			contents, err := container.File(f.Path).Contents(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", f.Path, err)
+			}
+			matched, err := regexp.MatchString(f.Contains, contents)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", f.Path, err)
+			}
+			if !matched {
+				add(name, false, fmt.Sprintf("content does not match %q", f.Contains))
+				continue
+			}
+		}
+		add(name, true, "")
+	}
+
+	// Command checks
+	for _, c := range s.Commands {
+		name := "command " + c.Name
+		if c.Name == "" {
+			name = "command " + strings.Join(c.Command, " ")
+		}
+
+		exec := container.WithExec(c.Command, dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+		exitCode, err := exec.ExitCode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", name, err)
+		}
+		stdout, err := exec.Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", name, err)
+		}
+
+		var failures []string
+		if exitCode != c.ExitCode {
+			failures = append(failures, fmt.Sprintf("exit code %d, expected %d", exitCode, c.ExitCode))
+		}
+		for _, pattern := range c.ExpectedOutput {
+			matched, err := regexp.MatchString(pattern, stdout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", name, err)
+			}
+			if !matched {
+				failures = append(failures, fmt.Sprintf("output does not match %q", pattern))
+			}
+		}
+		for _, pattern := range c.ExcludedOutput {
+			matched, err := regexp.MatchString(pattern, stdout)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for %s: %w", name, err)
+			}
+			if matched {
+				failures = append(failures, fmt.Sprintf("output matches excluded %q", pattern))
+			}
+		}
+		add(name, len(failures) == 0, strings.Join(failures, "; "))
+	}
+
+	// Metadata checks
+	if s.Metadata.Entrypoint != nil {
+		entrypoint, err := container.Entrypoint(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read entrypoint: %w", err)
+		}
+		add("metadata entrypoint", slices.Equal(entrypoint, s.Metadata.Entrypoint),
+			fmt.Sprintf("got %q, expected %q", entrypoint, s.Metadata.Entrypoint))
+	}
+
+	if s.Metadata.User != nil {
+		user, err := container.User(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read user: %w", err)
+		}
+		add("metadata user", user == *s.Metadata.User,
+			fmt.Sprintf("got %q, expected %q", user, *s.Metadata.User))
+	}
+
+	if s.Metadata.Workdir != nil {
+		workdir, err := container.Workdir(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read workdir: %w", err)
+		}
+		add("metadata workdir", workdir == *s.Metadata.Workdir,
+			fmt.Sprintf("got %q, expected %q", workdir, *s.Metadata.Workdir))
+	}
+
+	if len(s.Metadata.ExposedPorts) > 0 {
+		ports, err := container.ExposedPorts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read exposed ports: %w", err)
+		}
+		var exposed []string
+		for _, p := range ports {
+			port, err := p.Port(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read exposed ports: %w", err)
+			}
+			protocol, err := p.Protocol(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read exposed ports: %w", err)
+			}
+			exposed = append(exposed, fmt.Sprintf("%d/%s", port, strings.ToLower(string(protocol))))
+		}
+		for _, expected := range s.Metadata.ExposedPorts {
+			if !strings.Contains(expected, "/") {
+				expected += "/tcp"
+			}
+			add("metadata exposed port "+expected, slices.Contains(exposed, expected),
+				fmt.Sprintf("exposed ports: %s", strings.Join(exposed, ", ")))
+		}
+	}
+
+	// Sorted so reports can be compared between runs
+	for _, key := range slices.Sorted(maps.Keys(s.Metadata.Labels)) {
+		expected := s.Metadata.Labels[key]
+		value, err := container.Label(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read label %s: %w", key, err)
+		}
+		add("metadata label "+key, value == expected,
+			fmt.Sprintf("got %q, expected %q", value, expected))
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(s.Metadata.Env)) {
+		expected := s.Metadata.Env[key]
+		value, err := container.EnvVariable(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read env %s: %w", key, err)
+		}
+		add("metadata env "+key, value == expected,
+			fmt.Sprintf("got %q, expected %q", value, expected))
+	}
+
+	// Only keep messages for failed checks
+	report.Total = len(report.Results)
+	for _, r := range report.Results {
+		if r.Passed {
+			r.Message = ""
+		} else {
+			report.Failed++
+		}
+	}
+	report.Passed = report.Failed == 0
+
+	return report, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"dagger/docker/internal/dagger"
+)
+
+// WithBuildSecret adds a secret available to Dockerfile RUN instructions
+//
+// The secret is mounted only for RUN instructions that request it with
+// RUN --mount=type=secret,id=<id> and is readable at /run/secrets/<id>.
+// Unlike WithArg(), secrets are never stored in image history or labels.
+// Secrets whose name differs from the id (e.g., env:VAR_NAME) are mounted by
+// BuildKit directly, so the module never reads their value: such builds run a
+// privileged BuildKit container (InsecureRootCapabilities), like WithCache().
+// Adding a secret with an existing id replaces it.
+func (m *Docker) WithBuildSecret(
+	// Secret id referenced by --mount=type=secret,id=<id> (e.g., "npm_token")
+	id string,
+	// Secret value (use env:VAR_NAME or file:PATH)
+	secret *dagger.Secret,
+) (*Docker, error) {
+	if id == "" {
+		return nil, fmt.Errorf("build secret id cannot be empty")
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("build secret %s has no value", id)
+	}
+
+	d := m.clone()
+
+	newSecrets := make([]DockerBuildSecret, 0, len(d.BuildSecrets)+1)
+	for _, s := range d.BuildSecrets {
+		if s.ID != id {
+			newSecrets = append(newSecrets, s)
+		}
+	}
+	d.BuildSecrets = append(newSecrets, DockerBuildSecret{
+		ID:     id,
+		Secret: secret,
+	})
+
+	return d, nil
+}
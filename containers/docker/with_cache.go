@@ -0,0 +1,23 @@
+package main
+
+// WithCache configures a registry-backed build cache
+//
+// Layers are imported from the cache reference before each build and the cache
+// is refreshed after a successful push in BuildAndPush(). The cache registry is
+// authenticated with the credentials configured via WithRegistry().
+// When several platforms are built, each platform uses its own cache tag
+// (e.g., "app:buildcache-linux-arm64").
+// Cached builds run a privileged BuildKit container (InsecureRootCapabilities).
+func (m *Docker) WithCache(
+	// Registry cache reference (e.g., "myacr.azurecr.io/app:buildcache")
+	ref string,
+	// Treat cache import/export failures as warnings instead of errors
+	// +optional
+	// +default=false
+	ignoreErrors bool,
+) *Docker {
+	d := m.clone()
+	d.CacheRef = ref
+	d.CacheIgnoreErrors = ignoreErrors
+	return d
+}
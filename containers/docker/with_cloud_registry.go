@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"dagger/docker/internal/dagger"
+)
+
+// WithAcrRegistry adds Azure Container Registry authentication with Azure AD
+//
+// Exchanges a service principal secret or a federated token (workload identity,
+// CI OIDC) for an ACR refresh token, then behaves like WithRegistry().
+// Refresh tokens are valid for about three hours.
+func (m *Docker) WithAcrRegistry(
+	ctx context.Context,
+	// ACR hostname (e.g., "myregistry.azurecr.io")
+	host string,
+	// Azure AD tenant ID
+	tenantId string,
+	// Service principal or managed identity client ID
+	clientId string,
+	// Service principal client secret
+	// +optional
+	clientSecret *dagger.Secret,
+	// Federated token used as client assertion instead of a client secret
+	// +optional
+	federatedToken *dagger.Secret,
+	// Azure AD authority host
+	// +optional
+	// +default="https://login.microsoftonline.com"
+	authorityHost string,
+	// Only use this registry to pull base images, never push to it
+	// +optional
+	// +default=false
+	pullOnly bool,
+) (*Docker, error) {
+	token, err := m.AcrToken(ctx, host, tenantId, clientId, clientSecret, federatedToken, authorityHost)
+	if err != nil {
+		return nil, err
+	}
+	return m.WithRegistry(registryHostname(host), acrUsername, token, pullOnly), nil
+}
+
+// WithEcrRegistry adds Amazon ECR authentication with AWS credentials
+//
+// Exchanges the access key for an ECR password (like "aws ecr get-login-password"),
+// then behaves like WithRegistry(). ECR passwords are valid for 12 hours.
+func (m *Docker) WithEcrRegistry(
+	ctx context.Context,
+	// ECR hostname (e.g., "123456789012.dkr.ecr.eu-west-1.amazonaws.com")
+	host string,
+	// AWS access key ID
+	accessKeyId *dagger.Secret,
+	// AWS secret access key
+	secretAccessKey *dagger.Secret,
+	// AWS session token for temporary credentials
+	// +optional
+	sessionToken *dagger.Secret,
+	// AWS region (defaults to the region in the registry hostname)
+	// +optional
+	region string,
+	// ECR API endpoint (defaults to https://api.ecr.<region>.amazonaws.com)
+	// +optional
+	endpoint string,
+	// Only use this registry to pull base images, never push to it
+	// +optional
+	// +default=false
+	pullOnly bool,
+) (*Docker, error) {
+	host = registryHostname(host)
+	if region == "" {
+		matches := ecrHostPattern.FindStringSubmatch(host)
+		if matches == nil {
+			return nil, fmt.Errorf("cannot detect AWS region from %s: set region explicitly", host)
+		}
+		region = matches[1]
+	}
+
+	password, err := m.EcrPassword(ctx, region, accessKeyId, secretAccessKey, sessionToken, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return m.WithRegistry(host, ecrUsername, password, pullOnly), nil
+}
+
+// WithGcrRegistry adds Google Container/Artifact Registry authentication
+//
+// Exchanges a service account key for an OAuth access token, then behaves
+// like WithRegistry(). Access tokens are valid for one hour.
+func (m *Docker) WithGcrRegistry(
+	ctx context.Context,
+	// Registry hostname (e.g., "gcr.io", "europe-docker.pkg.dev")
+	host string,
+	// Service account key (JSON)
+	serviceAccountKey *dagger.Secret,
+	// OAuth token endpoint (defaults to the token_uri of the key)
+	// +optional
+	tokenEndpoint string,
+	// Only use this registry to pull base images, never push to it
+	// +optional
+	// +default=false
+	pullOnly bool,
+) (*Docker, error) {
+	token, err := m.GcrToken(ctx, serviceAccountKey, tokenEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return m.WithRegistry(registryHostname(host), gcrUsername, token, pullOnly), nil
+}
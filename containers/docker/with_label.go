@@ -0,0 +1,33 @@
+package main
+
+// WithLabel adds an image label (LABEL instruction)
+//
+// Labels are applied to every platform variant after the build and override
+// the OCI labels set automatically (e.g., org.opencontainers.image.title).
+// Setting an existing key replaces its value.
+func (m *Docker) WithLabel(
+	// Label key (e.g., "org.opencontainers.image.vendor")
+	key string,
+	// Label value
+	value string,
+) *Docker {
+	d := m.clone()
+	d.Labels = setKeyValue(d.Labels, key, value)
+	return d
+}
+
+// WithAnnotation adds a manifest annotation applied on Push
+//
+// Annotations are stored in the image manifest rather than the image config,
+// so registries can display them without pulling the image.
+// Setting an existing key replaces its value.
+func (m *Docker) WithAnnotation(
+	// Annotation key (e.g., "org.opencontainers.image.description")
+	key string,
+	// Annotation value
+	value string,
+) *Docker {
+	d := m.clone()
+	d.Annotations = setKeyValue(d.Annotations, key, value)
+	return d
+}
@@ -4,27 +4,24 @@ import (
 	"dagger/docker/internal/dagger"
 )
 
-// WithPlatform sets the target platform for the build
+// WithPlatform adds a target platform for the build
 //
-// Overrides the default platform (linux/amd64).
+// Chain multiple calls to build a multi-platform image (e.g., linux/amd64 and
+// linux/arm64). Push() then publishes all variants as a single image index.
+// Defaults to linux/amd64 when no platform is configured.
 func (m *Docker) WithPlatform(
 	// Target platform (e.g., "linux/amd64", "linux/arm64")
 	platform dagger.Platform,
 ) *Docker {
-	// Deep copy slices
-	newBuildArgs := make([]DockerBuildArg, len(m.BuildArgs))
-	copy(newBuildArgs, m.BuildArgs)
+	d := m.clone()
 
-	newTags := make([]string, len(m.Tags))
-	copy(newTags, m.Tags)
-
-	return &Docker{
-		BuildArgs:        newBuildArgs,
-		Tags:             newTags,
-		Target:           m.Target,
-		Platform:         platform,
-		RegistryHost:     m.RegistryHost,
-		RegistryUsername: m.RegistryUsername,
-		RegistryPassword: m.RegistryPassword,
+	// Ignore duplicates so the same variant is never built twice
+	for _, p := range d.Platforms {
+		if p == platform {
+			return d
+		}
 	}
+
+	d.Platforms = append(d.Platforms, platform)
+	return d
 }
@@ -0,0 +1,21 @@
+package main
+
+// WithProvenance enables SLSA provenance attestations on Push
+//
+// After each push, an in-toto statement with a SLSA v1 provenance predicate is
+// attached to the pushed digest as an OCI referrer artifact.
+// Use Provenance() to get the attestation as a file (e.g., for a compliance archive).
+func (m *Docker) WithProvenance(
+	// Builder identity recorded in the attestation (e.g., the CI workflow URL)
+	// +optional
+	// +default="urn:dagger:docker"
+	builderId string,
+) *Docker {
+	if builderId == "" {
+		builderId = defaultBuilderID
+	}
+
+	d := m.clone()
+	d.ProvenanceBuilder = builderId
+	return d
+}
@@ -4,10 +4,14 @@ import (
 	"dagger/docker/internal/dagger"
 )
 
-// WithRegistry configures Docker registry authentication for pushing images
+// WithRegistry adds Docker registry authentication
 //
 // Supports Azure ACR (*.azurecr.io) and other Docker-compatible registries.
 // Use environment variable references (env:VAR_NAME) for credentials.
+// Chain multiple calls to authenticate to several registries: credentials are
+// used to pull base images during Build() and images are pushed to every
+// registry not marked pull-only. Calling it again for the same host replaces
+// its credentials.
 func (m *Docker) WithRegistry(
 	// Registry hostname (e.g., "myregistry.azurecr.io")
 	host string,
@@ -15,21 +19,36 @@ func (m *Docker) WithRegistry(
 	username string,
 	// Registry password or token (use env:VAR_NAME for environment variables)
 	password *dagger.Secret,
+	// Only use this registry to pull base images, never push to it
+	// +optional
+	// +default=false
+	pullOnly bool,
 ) *Docker {
-	// Deep copy slices
-	newBuildArgs := make([]DockerBuildArg, len(m.BuildArgs))
-	copy(newBuildArgs, m.BuildArgs)
-
-	newTags := make([]string, len(m.Tags))
-	copy(newTags, m.Tags)
+	d := m.clone()
+	d.Registries = setRegistry(d.Registries, DockerRegistry{
+		Host:     host,
+		Username: username,
+		Password: password,
+		PullOnly: pullOnly,
+	})
+	return d
+}
 
-	return &Docker{
-		RegistryHost:     host,
-		RegistryUsername: username,
-		RegistryPassword: password,
-		BuildArgs:        newBuildArgs,
-		Tags:             newTags,
-		Target:           m.Target,
-		Platform:         m.Platform,
+// setRegistry returns a copy of registries with the entry for registry.Host replaced
+func setRegistry(registries []DockerRegistry, registry DockerRegistry) []DockerRegistry {
+	result := make([]DockerRegistry, 0, len(registries)+1)
+	replaced := false
+	for _, r := range registries {
+		if r.Host == registry.Host {
+			// Keep the original position so the primary registry does not change
+			result = append(result, registry)
+			replaced = true
+			continue
+		}
+		result = append(result, r)
+	}
+	if !replaced {
+		result = append(result, registry)
 	}
+	return result
 }
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+)
+
+// WithSbom enables SBOM generation on Push
+//
+// After each push, an SBOM is generated for every platform variant and attached
+// to the pushed digest as an OCI referrer artifact.
+// Use Sbom() to get the SBOM as a file (e.g., for a compliance archive).
+func (m *Docker) WithSbom(
+	// SBOM format ("spdx-json" or "cyclonedx-json")
+	// +optional
+	// +default="spdx-json"
+	format string,
+) (*Docker, error) {
+	if format == "" {
+		format = "spdx-json"
+	}
+	if _, ok := sbomArtifactTypes[format]; !ok {
+		return nil, fmt.Errorf("unsupported SBOM format: %s (supported: spdx-json, cyclonedx-json)", format)
+	}
+
+	d := m.clone()
+	d.SbomFormat = format
+	return d, nil
+}
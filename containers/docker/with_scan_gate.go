@@ -0,0 +1,32 @@
+package main
+
+import (
+	"dagger/docker/internal/dagger"
+)
+
+// WithScanGate enables a vulnerability scan before BuildAndPush() pushes
+//
+// Every built platform variant is scanned with Scan(). The push is refused when
+// a finding at or above the severity threshold is not in the allowlist.
+func (m *Docker) WithScanGate(
+	// Trivy cache directory containing an offline vulnerability database (db/trivy.db)
+	db *dagger.Directory,
+	// Minimum severity that blocks the push (UNKNOWN, LOW, MEDIUM, HIGH, CRITICAL)
+	// +optional
+	// +default="HIGH"
+	severity string,
+	// File listing accepted vulnerability IDs, one per line ("#" starts a comment)
+	// +optional
+	allowlist *dagger.File,
+) (*Docker, error) {
+	threshold, err := normalizeSeverity(severity)
+	if err != nil {
+		return nil, err
+	}
+
+	d := m.clone()
+	d.ScanDB = db
+	d.ScanSeverity = threshold
+	d.ScanAllowlist = allowlist
+	return d, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"dagger/docker/internal/dagger"
+)
+
+// WithSigningKey enables cosign image signing on Push
+//
+// Every digest published by Push() is signed and the signature is pushed next
+// to the image in the same repository. Use Verify() to check a signature.
+func (m *Docker) WithSigningKey(
+	// Cosign private key (use env:VAR_NAME or file:PATH)
+	key *dagger.Secret,
+	// Password of the private key
+	// +optional
+	password *dagger.Secret,
+	// Upload signatures to the public Rekor transparency log
+	// +optional
+	// +default=false
+	transparencyLog bool,
+) *Docker {
+	d := m.clone()
+	d.SigningKey = key
+	d.SigningPassword = password
+	d.SigningTlog = transparencyLog
+	return d
+}
@@ -2,9 +2,10 @@ package main
 
 // WithTag adds image tags.
 //
-// If the tag is a semantic version (e.g., "v1.2.3", "v1.0.0-rc4"), it automatically
-// generates all appropriate tags:
-//   - v1.0.0-dev    -> v1.0.0-dev, dev
+// If the tag is a semantic version (e.g., "v1.2.3", "v1.0.0-rc4"), it is expanded
+// into all appropriate tags when the image is built or pushed, according to the
+// tag policy (see WithTagPolicy()). With the default policy:
+//   - v1.0.0-dev    -> v1.0.0-dev, v1.0-dev, v1-dev, dev
 //   - v1.0.0-rc1    -> v1.0.0-rc1, v1.0.0-rc, v1.0-rc, v1-rc, rc
 //   - v1.0.0-release -> v1.0.0-release, v1.0.0, v1.0, v1, release, latest
 //   - v1.0.0        -> v1.0.0, v1.0, v1, latest
@@ -14,25 +15,7 @@ func (m *Docker) WithTag(
 	// Image tag (e.g., "v1.2.3", "v1.0.0-rc4", "latest", "dev")
 	tag string,
 ) *Docker {
-	// Parse semantic version tags
-	tags := parseSemanticVersion(tag)
-
-	// Deep copy build args slice
-	newBuildArgs := make([]DockerBuildArg, len(m.BuildArgs))
-	copy(newBuildArgs, m.BuildArgs)
-
-	// Deep copy tags slice (immutable pattern)
-	newTags := make([]string, len(m.Tags), len(m.Tags)+len(tags))
-	copy(newTags, m.Tags)
-	newTags = append(newTags, tags...)
-
-	return &Docker{
-		BuildArgs:        newBuildArgs,
-		Tags:             newTags,
-		Target:           m.Target,
-		Platform:         m.Platform,
-		RegistryHost:     m.RegistryHost,
-		RegistryUsername: m.RegistryUsername,
-		RegistryPassword: m.RegistryPassword,
-	}
+	d := m.clone()
+	d.Tags = append(d.Tags, tag)
+	return d
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithTagPolicy configures how semantic version tags are expanded
+//
+// Applies to tags added with WithTag() and WithGitTags(), regardless of the
+// order of the calls. The default policy uses the dev and rc channels, produces
+// floating major/minor tags, moves latest and strips build metadata.
+//
+// Prereleases whose suffix starts with a channel name (e.g., "beta" for
+// v1.0.0-beta.3) get channel tags (v1.0.0-beta, v1.0-beta, v1-beta, beta);
+// other prereleases only get their exact tag.
+func (m *Docker) WithTagPolicy(
+	// Prerelease channel names (e.g., "dev", "alpha", "beta", "rc")
+	// +optional
+	// +default=["dev", "rc"]
+	channels []string,
+	// Move the latest tag (and bare channel tags such as "rc") to the new version
+	// +optional
+	// +default=true
+	moveLatest bool,
+	// Produce floating major and minor tags (e.g., v1 and v1.2 for v1.2.3)
+	// +optional
+	// +default=true
+	floating bool,
+	// Build metadata handling for versions like v1.2.3+abc123: "strip" (v1.2.3) or "suffix" (v1.2.3_abc123)
+	// +optional
+	// +default="strip"
+	buildMetadata string,
+) (*Docker, error) {
+	if buildMetadata == "" {
+		buildMetadata = "strip"
+	}
+	if buildMetadata != "strip" && buildMetadata != "suffix" {
+		return nil, fmt.Errorf("invalid build metadata handling: %s (supported: strip, suffix)", buildMetadata)
+	}
+
+	normalized := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if channel == "" || sanitizeTag(channel) != channel {
+			return nil, fmt.Errorf("invalid channel name: %q", channel)
+		}
+		if channel == "release" || channel == "latest" {
+			return nil, fmt.Errorf("channel name %q is reserved", channel)
+		}
+		normalized = append(normalized, channel)
+	}
+
+	d := m.clone()
+	d.TagChannels = normalized
+	d.TagMoveLatest = moveLatest
+	d.TagFloating = floating
+	d.TagBuildMetadata = buildMetadata
+	return d, nil
+}
@@ -1,9 +1,33 @@
 package main
 
 import (
+	"strconv"
+	"time"
+
 	"dagger/file-sync/internal/dagger"
 )
 
+// hostKeyScript adds the scanned host keys matching a pinned fingerprint to
+// known_hosts and fails unless this scan found one
+const hostKeyScript = `set -e
+ssh-keyscan -p "$SSH_PORT" "$SSH_HOST" 2>/dev/null > /tmp/host_keys || true
+found=0
+while read -r line; do
+  case "$line" in "#"*|"") continue ;; esac
+  echo "$line" > /tmp/host_key
+  if [ "$(ssh-keygen -lf /tmp/host_key | awk '{print $2}')" = "$SSH_FINGERPRINT" ]; then
+    echo "$line" >> /root/.ssh/known_hosts
+    found=1
+  fi
+done < /tmp/host_keys
+rm -f /tmp/host_keys /tmp/host_key
+if [ "$found" = 0 ]; then
+  echo "no host key of $SSH_HOST:$SSH_PORT matches $SSH_FINGERPRINT" >&2
+  exit 1
+fi
+chmod 600 /root/.ssh/known_hosts
+`
+
 // buildContainer creates a container with rsync and SSH client configured for file sync
 func (m *FileSync) buildContainer(source *dagger.Directory) *dagger.Container {
 	container := dag.Container().
@@ -14,7 +38,7 @@ func (m *FileSync) buildContainer(source *dagger.Directory) *dagger.Container {
 	// Install rsync and openssh-client
 	container = container.WithExec([]string{
 		"sh", "-c",
-		"apk add --no-cache rsync openssh-client",
+		"apk add --no-cache rsync openssh-client openssh-keygen",
 	})
 
 	// Setup SSH directory and key
@@ -24,8 +48,26 @@ func (m *FileSync) buildContainer(source *dagger.Directory) *dagger.Container {
 		WithMountedSecret("/tmp/ssh_key", m.SSHKey).
 		WithExec([]string{"sh", "-c", "cp /tmp/ssh_key /root/.ssh/id_ed25519 && chmod 600 /root/.ssh/id_ed25519"})
 
-	// Configure SSH to skip host key verification (for CI/CD)
-	sshConfig := "Host *\n  StrictHostKeyChecking no\n  UserKnownHostsFile /dev/null\n  LogLevel ERROR\n"
+	// Verify the host key unless verification was explicitly disabled
+	switch {
+	case m.SSHKnownHosts != nil:
+		container = container.WithFile("/root/.ssh/known_hosts", m.SSHKnownHosts, dagger.ContainerWithFileOpts{
+			Permissions: 0600,
+		})
+	case m.SSHHostKeyFingerprint != "":
+		// Host keys must be scanned on every run
+		container = container.
+			WithEnvVariable("SSH_HOST", m.SSHHost).
+			WithEnvVariable("SSH_PORT", strconv.Itoa(m.SSHPort)).
+			WithEnvVariable("SSH_FINGERPRINT", m.SSHHostKeyFingerprint).
+			WithEnvVariable("DAGGER_CACHE_BUSTER", time.Now().String()).
+			WithExec([]string{"sh", "-c", hostKeyScript})
+	}
+
+	sshConfig := "Host *\n  StrictHostKeyChecking yes\n  UserKnownHostsFile /root/.ssh/known_hosts\n  LogLevel ERROR\n"
+	if m.SSHInsecureIgnoreHostKey {
+		sshConfig = "Host *\n  StrictHostKeyChecking no\n  UserKnownHostsFile /dev/null\n  LogLevel ERROR\n"
+	}
 	container = container.WithNewFile("/root/.ssh/config", sshConfig, dagger.ContainerWithNewFileOpts{
 		Permissions: 0600,
 	})
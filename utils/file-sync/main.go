@@ -7,7 +7,7 @@
 // Example usage:
 //
 //	dagger call -m utils/file-sync \
-//	  with-context --host 172.16.24.97 --user admin --ssh-key env:SSH_KEY \
+//	  with-context --host 172.16.24.97 --user admin --ssh-key env:SSH_KEY --known-hosts ~/.ssh/known_hosts \
 //	  with-file --local-path settings/settings.yaml --remote-path /nfs/app/settings/settings.yaml \
 //	  with-directory --local-path configs/ --remote-path /nfs/app/configs/ \
 //	  sync --source .
@@ -26,6 +26,12 @@ type FileSync struct {
 	SSHPort int
 	SSHKey  *dagger.Secret
 
+	// SSH host key verification: known_hosts file or pinned fingerprint,
+	// unless verification is explicitly disabled
+	SSHKnownHosts            *dagger.File
+	SSHHostKeyFingerprint    string
+	SSHInsecureIgnoreHostKey bool
+
 	// Files to synchronize
 	Files []*FileMapping
 
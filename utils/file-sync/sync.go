@@ -55,8 +55,8 @@ func (m *FileSync) Sync(
 	output.WriteString(fmt.Sprintf("Target: %s@%s\n", m.SSHUser, m.SSHHost))
 	output.WriteString(fmt.Sprintf("Options: compress=%t, delete=%t, dry-run=%t\n\n", compress, delete, dryRun))
 
-	// Build SSH command for rsync
-	sshCmd := fmt.Sprintf("ssh -p %d -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null", m.SSHPort)
+	// Build SSH command for rsync (host key checking is set in /root/.ssh/config)
+	sshCmd := fmt.Sprintf("ssh -p %d", m.SSHPort)
 
 	// Process files
 	for _, f := range m.Files {
@@ -1,16 +1,26 @@
 package main
 
 import (
+	"fmt"
+	"strings"
+
 	"dagger/file-sync/internal/dagger"
 )
 
 // WithContext configures SSH connection for remote file synchronization
 //
+// The host key is verified against a known_hosts file or a pinned fingerprint
+// (as printed by ssh-keygen -lf, e.g., "SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s").
+// Verification can only be disabled explicitly with insecureIgnoreHostKey.
+//
 // Parameters:
 //   - host: Remote host IP address or hostname
 //   - user: SSH username for authentication
 //   - port: SSH port (default: 22)
 //   - sshKey: SSH private key for authentication
+//   - knownHosts: known_hosts file containing the host key (optional)
+//   - hostKeyFingerprint: Pinned host key fingerprint (optional)
+//   - insecureIgnoreHostKey: Skip host key verification, vulnerable to MITM (default: false)
 //
 // Example:
 //
@@ -18,6 +28,7 @@ import (
 //	  --host 172.16.24.97 \
 //	  --user admincd24 \
 //	  --ssh-key env:SSH_PRIVATE_KEY \
+//	  --host-key-fingerprint SHA256:uNiVztksCsDhcc0u9e8BujQXVUpKZIDTMczCvj3tD2s \
 //	  sync --source .
 func (m *FileSync) WithContext(
 	host string,
@@ -26,19 +37,51 @@ func (m *FileSync) WithContext(
 	// +default=22
 	port int,
 	sshKey *dagger.Secret,
-) *FileSync {
+	// +optional
+	knownHosts *dagger.File,
+	// +optional
+	hostKeyFingerprint string,
+	// +optional
+	// +default=false
+	insecureIgnoreHostKey bool,
+) (*FileSync, error) {
 	if port == 0 {
 		port = 22
 	}
+	if err := validateHostKeyOptions(knownHosts, hostKeyFingerprint, insecureIgnoreHostKey); err != nil {
+		return nil, err
+	}
 
 	return &FileSync{
-		SSHHost:     host,
-		SSHUser:     user,
-		SSHPort:     port,
-		SSHKey:      sshKey,
-		Files:       copyFiles(m.Files),
-		Directories: copyDirectories(m.Directories),
+		SSHHost:                  host,
+		SSHUser:                  user,
+		SSHPort:                  port,
+		SSHKey:                   sshKey,
+		SSHKnownHosts:            knownHosts,
+		SSHHostKeyFingerprint:    hostKeyFingerprint,
+		SSHInsecureIgnoreHostKey: insecureIgnoreHostKey,
+		Files:                    copyFiles(m.Files),
+		Directories:              copyDirectories(m.Directories),
+	}, nil
+}
+
+// validateHostKeyOptions requires exactly one host key verification option
+func validateHostKeyOptions(knownHosts *dagger.File, fingerprint string, insecure bool) error {
+	options := 0
+	for _, set := range []bool{knownHosts != nil, fingerprint != "", insecure} {
+		if set {
+			options++
+		}
+	}
+	switch {
+	case options == 0:
+		return fmt.Errorf("host key verification required: provide knownHosts or hostKeyFingerprint (or insecureIgnoreHostKey to disable verification)")
+	case options > 1:
+		return fmt.Errorf("knownHosts, hostKeyFingerprint and insecureIgnoreHostKey are mutually exclusive")
+	case fingerprint != "" && !strings.HasPrefix(fingerprint, "SHA256:"):
+		return fmt.Errorf("invalid host key fingerprint %q: expected SHA256:... as printed by ssh-keygen -lf", fingerprint)
 	}
+	return nil
 }
 
 // copyFiles creates a deep copy of the files slice
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestValidateHostKeyOptions(t *testing.T) {
+	fingerprint := "SHA256:Dlnh/skgsuA0FHw6gwpZ9yFVjpDUYcFALkS/sv7rm9E"
+	tests := []struct {
+		name        string
+		fingerprint string
+		insecure    bool
+		wantErr     bool
+	}{
+		{"pinned fingerprint", fingerprint, false, false},
+		{"verification explicitly disabled", "", true, false},
+		{"no verification option", "", false, true},
+		{"fingerprint and insecure", fingerprint, true, true},
+	}
+	for _, tt := range tests {
+		if err := validateHostKeyOptions(nil, tt.fingerprint, tt.insecure); (err != nil) != tt.wantErr {
+			t.Errorf("%s: validateHostKeyOptions() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
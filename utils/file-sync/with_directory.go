@@ -37,11 +37,14 @@ func (m *FileSync) WithDirectory(
 	})
 
 	return &FileSync{
-		SSHHost:     m.SSHHost,
-		SSHUser:     m.SSHUser,
-		SSHPort:     m.SSHPort,
-		SSHKey:      m.SSHKey,
-		Files:       copyFiles(m.Files),
-		Directories: newDirs,
+		SSHHost:                  m.SSHHost,
+		SSHUser:                  m.SSHUser,
+		SSHPort:                  m.SSHPort,
+		SSHKey:                   m.SSHKey,
+		SSHKnownHosts:            m.SSHKnownHosts,
+		SSHHostKeyFingerprint:    m.SSHHostKeyFingerprint,
+		SSHInsecureIgnoreHostKey: m.SSHInsecureIgnoreHostKey,
+		Files:                    copyFiles(m.Files),
+		Directories:              newDirs,
 	}
 }
@@ -31,11 +31,14 @@ func (m *FileSync) WithFile(
 	})
 
 	return &FileSync{
-		SSHHost:     m.SSHHost,
-		SSHUser:     m.SSHUser,
-		SSHPort:     m.SSHPort,
-		SSHKey:      m.SSHKey,
-		Files:       newFiles,
-		Directories: copyDirectories(m.Directories),
+		SSHHost:                  m.SSHHost,
+		SSHUser:                  m.SSHUser,
+		SSHPort:                  m.SSHPort,
+		SSHKey:                   m.SSHKey,
+		SSHKnownHosts:            m.SSHKnownHosts,
+		SSHHostKeyFingerprint:    m.SSHHostKeyFingerprint,
+		SSHInsecureIgnoreHostKey: m.SSHInsecureIgnoreHostKey,
+		Files:                    newFiles,
+		Directories:              copyDirectories(m.Directories),
 	}
 }